@@ -15,12 +15,62 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
 	"github.com/pulumi/pulumi-xyz/pkg/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
 )
 
 var providerName = "xyz"
 
+// recordEnvVar names a file to which the provider appends every request and response.
+const recordEnvVar = "PULUMI_XYZ_RECORD"
+
+// validateOutputsEnvVar turns on validation of resource outputs against the schema: "warn" logs mismatches, "error"
+// fails the request.
+const validateOutputsEnvVar = "PULUMI_XYZ_VALIDATE_OUTPUTS"
+
 func main() {
-	provider.Serve(providerName, version.Version)
+	registry := resources.NewRegistry(providerName)
+	if err := resources.Register(registry); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+
+	metadata, err := resources.PackageMetadata()
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	metadata.Version = version.Version
+
+	opts := provider.Options{
+		Registry: registry,
+		Metadata: metadata,
+	}
+
+	switch mode := os.Getenv(validateOutputsEnvVar); mode {
+	case "":
+	case "warn":
+		opts.OutputValidation = provider.OutputValidationWarn
+	case "error":
+		opts.OutputValidation = provider.OutputValidationError
+	default:
+		cmdutil.ExitError(fmt.Sprintf("%s must be \"warn\" or \"error\", got %q", validateOutputsEnvVar, mode))
+	}
+
+	// Record the session with the engine for regression tests, see providertest.Replay.
+	if path := os.Getenv(recordEnvVar); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			cmdutil.ExitError(err.Error())
+		}
+		defer contract.IgnoreClose(f)
+		redactor := provider.NewRedactor(registry, opts.Config)
+		opts.Middlewares = append(opts.Middlewares, provider.Recorder(f, redactor))
+	}
+
+	provider.Serve(opts)
 }
@@ -0,0 +1,71 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pulumi/pulumi-xyz/pkg/schemadiff"
+)
+
+// compareSchemas reports the changes from the schema of the previous release to the current one, optionally as a
+// changelog, and returns the exit code, which is a failure for unacknowledged breaking changes.
+func compareSchemas(opts options, stdout, stderr io.Writer) int {
+	old, err := readSchema(opts.compare)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	spec, err := packageSpec(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+
+	changes := schemadiff.Compare(old, spec)
+	for _, c := range changes {
+		if c.Breaking {
+			fmt.Fprintf(stdout, "breaking: %v\n", c)
+		} else {
+			fmt.Fprintf(stdout, "additive: %v\n", c)
+		}
+	}
+
+	if opts.changelog != "" {
+		title := opts.version
+		if title == "" {
+			title = "Unreleased"
+		}
+		if err := ioutil.WriteFile(opts.changelog, schemadiff.Changelog(title, changes), 0644); err != nil {
+			fmt.Fprintf(stderr, "error: writing changelog: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	breaking := len(schemadiff.Breaking(changes))
+	switch {
+	case breaking == 0:
+		return exitOK
+	case opts.allowBreaking:
+		fmt.Fprintf(stderr, "warning: %d breaking changes acknowledged with --allow-breaking\n", breaking)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "error: %d breaking changes since %s, pass --allow-breaking if they are intended\n",
+			breaking, opts.compare)
+		return exitFailure
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tools"
+)
+
+// writeFiles writes the generated files, creating directories as needed.
+func writeFiles(files map[string][]byte) error {
+	for _, name := range sortedFiles(files) {
+		if err := tools.EnsureDir(filepath.Dir(name)); err != nil {
+			return errors.Wrapf(err, "creating directory for %s", name)
+		}
+		if err := ioutil.WriteFile(name, files[name], 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", name)
+		}
+	}
+	return nil
+}
+
+// checkFiles compares the generated files to the files on disk and describes each file that is missing or differs.
+func checkFiles(files map[string][]byte) ([]string, error) {
+	var drift []string
+	for _, name := range sortedFiles(files) {
+		contents, err := ioutil.ReadFile(name)
+		switch {
+		case os.IsNotExist(err):
+			drift = append(drift, fmt.Sprintf("missing: %s", name))
+		case err != nil:
+			return nil, errors.Wrapf(err, "reading %s", name)
+		case !bytes.Equal(contents, files[name]):
+			drift = append(drift, fmt.Sprintf("modified: %s", name))
+		}
+	}
+	return drift, nil
+}
+
+func sortedFiles(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,115 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	dotnetgen "github.com/pulumi/pulumi/pkg/v3/codegen/dotnet"
+	gogen "github.com/pulumi/pulumi/pkg/v3/codegen/go"
+	nodejsgen "github.com/pulumi/pulumi/pkg/v3/codegen/nodejs"
+	pygen "github.com/pulumi/pulumi/pkg/v3/codegen/python"
+	pschema "github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+// languages lists the SDK languages the generator supports.
+var languages = []string{"dotnet", "go", "nodejs", "python"}
+
+const toolDescription = "the Pulumi SDK Generator"
+
+// generate builds the package schema and returns the generated files keyed by their path. SDK files are placed in a
+// folder per language in the target folder, together with their manifest.
+func generate(opts options) (map[string][]byte, error) {
+	spec, err := packageSpec(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ppkg, err := pschema.ImportSpec(spec, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading schema")
+	}
+
+	files := map[string][]byte{}
+	for _, lang := range opts.languages {
+		sdk, err := generateSDK(lang, ppkg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "generating %s package", lang)
+		}
+		names := make([]string, 0, len(sdk))
+		for name, contents := range sdk {
+			files[filepath.Join(opts.outDir, lang, filepath.FromSlash(name))] = contents
+			names = append(names, name)
+		}
+		files[filepath.Join(opts.outDir, lang, manifestFile)] = manifest(names)
+	}
+
+	if opts.schemaOut != "" {
+		contents, err := marshalSchema(spec, isYAML(opts.schemaOut))
+		if err != nil {
+			return nil, errors.Wrap(err, "marshaling schema")
+		}
+		files[opts.schemaOut] = contents
+	}
+	return files, nil
+}
+
+// packageSpec reads the package schema from the schema file given with --schema-in, or builds it from the registered
+// resources and the package metadata.
+func packageSpec(opts options) (pschema.PackageSpec, error) {
+	if opts.schemaIn != "" {
+		spec, err := readSchema(opts.schemaIn)
+		if err != nil {
+			return pschema.PackageSpec{}, errors.Wrap(err, "reading schema")
+		}
+		if opts.version != "" {
+			spec.Version = opts.version
+		}
+		return spec, nil
+	}
+
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		return pschema.PackageSpec{}, errors.Wrap(err, "registering resources")
+	}
+	metadata, err := resources.PackageMetadata()
+	if err != nil {
+		return pschema.PackageSpec{}, err
+	}
+	metadata.Version = opts.version
+	spec, err := registry.PackageSpec(metadata, pschema.ConfigSpec{})
+	if err != nil {
+		return pschema.PackageSpec{}, errors.Wrap(err, "building schema")
+	}
+	return spec, nil
+}
+
+func generateSDK(lang string, ppkg *pschema.Package) (map[string][]byte, error) {
+	extraFiles := map[string][]byte{}
+	switch lang {
+	case "dotnet":
+		return dotnetgen.GeneratePackage(toolDescription, ppkg, extraFiles)
+	case "go":
+		return gogen.GeneratePackage(toolDescription, ppkg)
+	case "nodejs":
+		return nodejsgen.GeneratePackage(toolDescription, ppkg, extraFiles)
+	case "python":
+		return pygen.GeneratePackage(toolDescription, ppkg, extraFiles)
+	default:
+		return nil, errors.Errorf("unknown language %q", lang)
+	}
+}
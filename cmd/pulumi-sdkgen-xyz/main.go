@@ -15,122 +15,192 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"io"
 	"os"
-	"path"
-
-	"github.com/pulumi/pulumi/sdk/v3/go/common/tools"
-	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
-
-	"github.com/pkg/errors"
-	dotnetgen "github.com/pulumi/pulumi/pkg/v3/codegen/dotnet"
-	gogen "github.com/pulumi/pulumi/pkg/v3/codegen/go"
-	nodejsgen "github.com/pulumi/pulumi/pkg/v3/codegen/nodejs"
-	pygen "github.com/pulumi/pulumi/pkg/v3/codegen/python"
-	pschema "github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"path/filepath"
+	"strings"
 )
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: pulumi-sdkgen-xyz <target-sdk-folder>\n")
-		return
-	}
+// Exit codes of the generator. Usage errors exit with 2, like the flag package.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
 
-	targetSdkFolder := os.Args[1]
+// schemaFile is the name of the package schema in the target folder.
+const schemaFile = "schema.json"
+
+// options are the command line settings of a run.
+type options struct {
+	// Directory that holds one folder per language.
+	outDir string
+	// Languages to generate, a subset of languages.
+	languages []string
+	// Version written into the package schema.
+	version string
+	// Path to write the package schema to, as YAML if it has a .yaml or .yml extension. Empty to not write it.
+	schemaOut string
+	// Path to read the package schema from instead of building it from the registered resources. Optional.
+	schemaIn string
+	// Compare the generated files to the files on disk instead of writing them.
+	check bool
+	// List the files that would be written and deleted instead of changing them.
+	dryRun bool
+	// Path to the schema of the previous release to report changes against, instead of generating. Optional.
+	compare string
+	// Do not fail on breaking changes when comparing schemas.
+	allowBreaking bool
+	// Path to write a markdown changelog of the changes found by comparing schemas to. Optional.
+	changelog string
+}
 
-	err := emitPackage(targetSdkFolder)
-	if err != nil {
-		fmt.Printf("Failed: %s", err.Error())
-	}
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
 }
 
-// emitPackage emits an entire package pack into the configured output directory with the configured settings.
-func emitPackage(targetSdkFolder string) error {
-	spec := pschema.PackageSpec{
-		Name:      "xyz",
-		Resources: map[string]pschema.ResourceSpec{},
-		Language: map[string]json.RawMessage{
-			"nodejs": rawMessage(map[string]interface{}{
-				"dependencies": map[string]string{
-					"@pulumi/pulumi": "^3.0.0",
-				},
-			}),
-			"python": rawMessage(map[string]interface{}{
-				"usesIOClasses": true,
-			}),
-			"csharp": rawMessage(map[string]interface{}{
-				"packageReferences": map[string]string{
-					"Pulumi":                       "3.*",
-					"System.Collections.Immutable": "1.6.0",
-				},
-			}),
-			"go": rawMessage(map[string]interface{}{}),
-		},
+// run generates the SDKs as configured by the command line arguments and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
 	}
 
-	for tok, res := range resources.Resources {
-		spec.Resources[tok] = *res.Schema
+	if opts.compare != "" {
+		return compareSchemas(opts, stdout, stderr)
 	}
 
-	ppkg, err := pschema.ImportSpec(spec, nil)
+	files, err := generate(opts)
 	if err != nil {
-		return errors.Wrap(err, "reading schema")
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
 	}
-
-	toolDescription := "the Pulumi SDK Generator"
-	extraFiles := map[string][]byte{}
-
-	sdkGenerators := map[string]func() (map[string][]byte, error){
-		"python": func() (map[string][]byte, error) {
-			return pygen.GeneratePackage(toolDescription, ppkg, extraFiles)
-		},
-		"nodejs": func() (map[string][]byte, error) {
-			return nodejsgen.GeneratePackage(toolDescription, ppkg, extraFiles)
-		},
-		"go": func() (map[string][]byte, error) {
-			return gogen.GeneratePackage(toolDescription, ppkg)
-		},
-		"dotnet": func() (map[string][]byte, error) {
-			return dotnetgen.GeneratePackage(toolDescription, ppkg, extraFiles)
-		},
+	stale, err := staleFiles(opts, files)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
 	}
 
-	for sdkName, generator := range sdkGenerators {
-		files, err := generator()
+	switch {
+	case opts.dryRun:
+		for _, name := range sortedFiles(files) {
+			fmt.Fprintf(stdout, "write %s\n", name)
+		}
+		for _, name := range stale {
+			fmt.Fprintf(stdout, "delete %s\n", name)
+		}
+	case opts.check:
+		drift, err := checkFiles(files)
 		if err != nil {
-			return errors.Wrapf(err, "generating %s package", sdkName)
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitFailure
 		}
-
-		for f, contents := range files {
-			if err := emitFile(path.Join(targetSdkFolder, sdkName), f, contents); err != nil {
-				return errors.Wrapf(err, "emitting file %v", f)
+		for _, name := range stale {
+			if _, err := os.Stat(name); err == nil {
+				drift = append(drift, fmt.Sprintf("stale: %s", name))
 			}
 		}
+		if len(drift) > 0 {
+			for _, msg := range drift {
+				fmt.Fprintln(stderr, msg)
+			}
+			fmt.Fprintln(stderr, "error: generated files are out of date, run `make generate`")
+			return exitFailure
+		}
+	default:
+		if err := writeFiles(files); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitFailure
+		}
+		if err := removeFiles(opts.outDir, stale); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitFailure
+		}
 	}
-
-	return nil
+	return exitOK
 }
 
-func emitFile(outDir, relPath string, contents []byte) error {
-	p := path.Join(outDir, relPath)
-	if err := tools.EnsureDir(path.Dir(p)); err != nil {
-		return errors.Wrap(err, "creating directory")
+func parseArgs(args []string, stderr io.Writer) (options, error) {
+	flags := flag.NewFlagSet("pulumi-sdkgen-xyz", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: pulumi-sdkgen-xyz [flags] <target-sdk-folder>\n\nFlags:\n")
+		flags.PrintDefaults()
 	}
 
-	f, err := os.Create(p)
-	if err != nil {
-		return errors.Wrap(err, "creating file")
+	var opts options
+	langs := flags.String("language", strings.Join(languages, ","),
+		"comma-separated languages to generate")
+	flags.StringVar(&opts.version, "version", "", "version of the package")
+	flags.StringVar(&opts.schemaOut, "schema-out", "",
+		"write the package schema to this path, as YAML for .yaml and .yml files "+
+			"(default <target-sdk-folder>/schema.json unless --schema-in is given)")
+	flags.StringVar(&opts.schemaIn, "schema-in", "",
+		"generate the SDKs from this JSON or YAML schema file instead of from the registered resources")
+	flags.BoolVar(&opts.check, "check", false,
+		"compare the generated files to the files on disk and fail if they differ, without writing")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "list the files that would be written and deleted, without changing them")
+	flags.StringVar(&opts.compare, "compare", "",
+		"report the changes since the schema of the previous release at this path and fail on breaking ones, "+
+			"without generating")
+	flags.BoolVar(&opts.allowBreaking, "allow-breaking", false, "acknowledge breaking changes found by --compare")
+	flags.StringVar(&opts.changelog, "changelog", "",
+		"write the changes found by --compare as a markdown changelog section, titled with --version, to this path")
+	if err := flags.Parse(args); err != nil {
+		return options{}, err
 	}
-	defer contract.IgnoreClose(f)
 
-	_, err = f.Write(contents)
-	return err
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return options{}, fmt.Errorf("expected a single target folder, got %d arguments", flags.NArg())
+	}
+	opts.outDir = filepath.Clean(flags.Arg(0))
+	schemaOutSet := false
+	flags.Visit(func(f *flag.Flag) {
+		schemaOutSet = schemaOutSet || f.Name == "schema-out"
+	})
+	if !schemaOutSet && opts.schemaIn == "" {
+		opts.schemaOut = filepath.Join(opts.outDir, schemaFile)
+	}
+	modes := 0
+	for _, set := range []bool{opts.check, opts.dryRun, opts.compare != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return options{}, fmt.Errorf("--check, --dry-run and --compare cannot be combined")
+	}
+	if opts.changelog != "" && opts.compare == "" {
+		return options{}, fmt.Errorf("--changelog requires --compare")
+	}
+
+	seen := map[string]bool{}
+	for _, lang := range strings.Split(*langs, ",") {
+		lang = strings.TrimSpace(lang)
+		if !isLanguage(lang) {
+			return options{}, fmt.Errorf("unknown language %q, expected one of %s", lang,
+				strings.Join(languages, ", "))
+		}
+		if !seen[lang] {
+			seen[lang] = true
+			opts.languages = append(opts.languages, lang)
+		}
+	}
+	return opts, nil
 }
 
-func rawMessage(v interface{}) json.RawMessage {
-	bytes, err := json.Marshal(v)
-	contract.Assert(err == nil)
-	return bytes
+func isLanguage(lang string) bool {
+	for _, l := range languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
 }
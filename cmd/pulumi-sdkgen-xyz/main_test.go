@@ -0,0 +1,222 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"a", "b"},
+		{"--language", "cobol", "sdk"},
+		{"--check", "--dry-run", "sdk"},
+		{"--unknown", "sdk"},
+		{"--changelog", "CHANGELOG.md", "sdk"},
+	} {
+		var stderr bytes.Buffer
+		if code := run(args, ioutil.Discard, &stderr); code != exitUsage {
+			t.Errorf("expected exit code %d for %v, got %d: %s", exitUsage, args, code, stderr.String())
+		}
+	}
+}
+
+func TestGenerateAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	sdkDir := filepath.Join(dir, "sdk")
+	schemaPath := filepath.Join(dir, "schema.json")
+	args := []string{"--language", "go", "--schema-out", schemaPath, sdkDir}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append([]string{"--dry-run"}, args...), &stdout, &stderr); code != exitOK {
+		t.Fatalf("dry run failed with %d: %s", code, stderr.String())
+	}
+	var listed []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		listed = append(listed, strings.TrimPrefix(line, "write "))
+	}
+	if len(listed) < 2 || listed[0] != schemaPath {
+		t.Errorf("expected the dry run to list the schema and the Go SDK, got %v", listed)
+	}
+	if _, err := os.Stat(sdkDir); !os.IsNotExist(err) {
+		t.Errorf("expected the dry run not to write files, got %v", err)
+	}
+
+	if code := run(append([]string{"--check"}, args...), ioutil.Discard, &stderr); code != exitFailure {
+		t.Errorf("expected the check of missing files to fail, got %d", code)
+	}
+	if code := run(args, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("generating failed with %d: %s", code, stderr.String())
+	}
+	for _, name := range listed {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	if code := run(append([]string{"--check"}, args...), ioutil.Discard, &stderr); code != exitOK {
+		t.Errorf("expected the check of fresh files to pass, got %d: %s", code, stderr.String())
+	}
+
+	if err := ioutil.WriteFile(listed[len(listed)-1], []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := run(append([]string{"--check"}, args...), ioutil.Discard, &stderr); code != exitFailure {
+		t.Errorf("expected the check of an edited file to fail, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "modified: "+listed[len(listed)-1]) {
+		t.Errorf("expected the edited file to be reported, got %s", stderr.String())
+	}
+}
+
+func TestStaleFilesAreRemoved(t *testing.T) {
+	sdkDir := filepath.Join(t.TempDir(), "sdk")
+	args := []string{"--language", "go", sdkDir}
+	if code := run(args, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Fatalf("generating failed with %d", code)
+	}
+
+	// Pretend that an earlier version generated a resource that has since been removed, next to a hand-written file.
+	goDir := filepath.Join(sdkDir, "go")
+	stale := filepath.Join(goDir, "xyz", "old", "widget.go")
+	overlay := filepath.Join(goDir, "xyz", "overlay.go")
+	for _, name := range []string{stale, overlay} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte("package xyz\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestPath := filepath.Join(goDir, manifestFile)
+	contents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(manifestPath, append(contents, "xyz/old/widget.go\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append([]string{"--dry-run"}, args...), &stdout, ioutil.Discard); code != exitOK ||
+		!strings.Contains(stdout.String(), "delete "+stale+"\n") {
+		t.Errorf("expected the dry run to list the stale file, got %d: %s", code, stdout.String())
+	}
+	if code := run(append([]string{"--check"}, args...), ioutil.Discard, &stderr); code != exitFailure ||
+		!strings.Contains(stderr.String(), "stale: "+stale) {
+		t.Errorf("expected the check to report the stale file, got %d: %s", code, stderr.String())
+	}
+
+	if code := run(args, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Fatalf("regenerating failed with %d", code)
+	}
+	if _, err := os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Errorf("expected the stale file and its directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(overlay); err != nil {
+		t.Errorf("expected the hand-written file to be kept: %v", err)
+	}
+	if code := run(append([]string{"--check"}, args...), ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Errorf("expected the check to pass after regenerating, got %d", code)
+	}
+}
+
+func TestGenerateFromSchema(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"schema.json", "schema.yaml"} {
+		schemaPath := filepath.Join(dir, name)
+		args := []string{"--language", "go", "--schema-out", schemaPath, filepath.Join(dir, "from-code")}
+		if code := run(args, ioutil.Discard, ioutil.Discard); code != exitOK {
+			t.Fatalf("generating from code failed with %d", code)
+		}
+
+		// SDKs generated from the written schema match those generated from code.
+		var stderr bytes.Buffer
+		args = []string{"--check", "--language", "go", "--schema-in", schemaPath, filepath.Join(dir, "from-code")}
+		if code := run(args, ioutil.Discard, &stderr); code != exitOK {
+			t.Errorf("expected SDKs generated from %s to match, got %d: %s", name, code, stderr.String())
+		}
+	}
+
+	// Reading and writing a schema is stable.
+	spec, err := readSchema(filepath.Join(dir, "schema.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := marshalSchema(spec, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCode, err := ioutil.ReadFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fromYAML, fromCode) {
+		t.Errorf("expected the YAML schema to read back as\n%s\ngot\n%s", fromCode, fromYAML)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.json")
+	if code := run([]string{"--language", "go", "--schema-out", schemaPath, dir}, ioutil.Discard,
+		ioutil.Discard); code != exitOK {
+		t.Fatalf("generating failed with %d", code)
+	}
+	if code := run([]string{"--compare", schemaPath, dir}, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Errorf("expected no breaking changes against the current schema, got %d", code)
+	}
+
+	// A previous release had a resource that is gone now.
+	spec, err := readSchema(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Resources["xyz:index:Removed"] = spec.Resources["xyz:index:RandomString"]
+	contents, err := marshalSchema(spec, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(schemaPath, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if code := run([]string{"--compare", schemaPath, dir}, &stdout, ioutil.Discard); code != exitFailure {
+		t.Errorf("expected the breaking change to fail, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), `breaking: resource "xyz:index:Removed" was removed`) {
+		t.Errorf("expected the removed resource to be reported, got %s", stdout.String())
+	}
+	changelogPath := filepath.Join(dir, "CHANGELOG.md")
+	if code := run([]string{"--compare", schemaPath, "--allow-breaking", "--version", "v1.1.0",
+		"--changelog", changelogPath, dir}, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Errorf("expected acknowledged breaking changes to pass, got %d", code)
+	}
+	changelog, err := ioutil.ReadFile(changelogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(changelog), "## v1.1.0\n") ||
+		!strings.Contains(string(changelog), "- **Breaking:** Resource `xyz:index:Removed` was removed\n") {
+		t.Errorf("expected a changelog with the removed resource, got %s", changelog)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// manifestFile lists the files generated for a language, relative to the folder of the language. Files that were
+// generated before but are no longer are deleted. Files that are not listed, e.g. hand-written overlays and the
+// placeholder go.mod files, are never touched.
+const manifestFile = ".sdkgen-manifest"
+
+const manifestHeader = "# Files generated by pulumi-sdkgen-xyz. Do not edit."
+
+// manifest renders the manifest of the generated files of a language.
+func manifest(names []string) []byte {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	buf.WriteString(manifestHeader + "\n")
+	for _, name := range sorted {
+		buf.WriteString(name + "\n")
+	}
+	return buf.Bytes()
+}
+
+// readManifest returns the files listed in the manifest of a language folder, as paths. A missing manifest lists no
+// files.
+func readManifest(langDir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(langDir, manifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(line))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, errors.Errorf("%s lists %q, which is outside of %s", manifestFile, line, langDir)
+		}
+		names = append(names, filepath.Join(langDir, name))
+	}
+	return names, scanner.Err()
+}
+
+// staleFiles returns the files listed in the manifests of the generated languages that are no longer generated.
+func staleFiles(opts options, files map[string][]byte) ([]string, error) {
+	var stale []string
+	for _, lang := range opts.languages {
+		listed, err := readManifest(filepath.Join(opts.outDir, lang))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading the %s manifest", lang)
+		}
+		for _, name := range listed {
+			if _, ok := files[name]; !ok {
+				stale = append(stale, name)
+			}
+		}
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// removeFiles deletes stale files, and the directories that are left empty up to the target folder.
+func removeFiles(outDir string, stale []string) error {
+	for _, name := range stale {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "removing %s", name)
+		}
+		for dir := filepath.Dir(name); dir != outDir && strings.HasPrefix(dir, outDir); dir = filepath.Dir(dir) {
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+				break
+			}
+			if err := os.Remove(dir); err != nil {
+				return errors.Wrapf(err, "removing %s", dir)
+			}
+		}
+	}
+	return nil
+}
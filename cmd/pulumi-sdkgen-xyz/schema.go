@@ -0,0 +1,104 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	pschema "github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// isYAML reports whether a schema path should hold YAML rather than JSON.
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// marshalSchema renders the package schema in canonical form: object keys are in a fixed order, struct fields as
+// declared and map keys sorted, with four spaces of indentation. YAML keeps the key order of the JSON form.
+func marshalSchema(spec pschema.PackageSpec, asYAML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(spec); err != nil {
+		return nil, err
+	}
+	if !asYAML {
+		return buf.Bytes(), nil
+	}
+
+	// JSON is YAML, so decoding it into a node keeps the key order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &node); err != nil {
+		return nil, err
+	}
+	resetStyle(&node)
+	var out bytes.Buffer
+	yamlEnc := yaml.NewEncoder(&out)
+	yamlEnc.SetIndent(2)
+	if err := yamlEnc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := yamlEnc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// resetStyle switches nodes decoded from JSON to block style, so that they are written as idiomatic YAML.
+func resetStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode || node.Style == yaml.DoubleQuotedStyle {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// readSchema reads a package schema from a JSON or YAML file.
+func readSchema(path string) (pschema.PackageSpec, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return pschema.PackageSpec{}, err
+	}
+
+	if isYAML(path) {
+		var v interface{}
+		if err := yaml.Unmarshal(contents, &v); err != nil {
+			return pschema.PackageSpec{}, errors.Wrapf(err, "parsing %s", path)
+		}
+		// Language settings are kept as raw JSON, so they must not be escaped differently from the JSON form.
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return pschema.PackageSpec{}, errors.Wrapf(err, "parsing %s", path)
+		}
+		contents = buf.Bytes()
+	}
+
+	var spec pschema.PackageSpec
+	if err := json.Unmarshal(contents, &spec); err != nil {
+		return pschema.PackageSpec{}, errors.Wrapf(err, "parsing %s", path)
+	}
+	return spec, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+const (
+	defaultAutoNameCharset      = "abcdefghijklmnopqrstuvwxyz0123456789"
+	defaultAutoNameSuffixLength = 7
+)
+
+// applyAutoName fills in the auto-named property of a resource if the user omitted it. The name of an existing
+// resource is taken from its old inputs, so it stays stable across updates; new resources get a name built from the
+// logical name in the URN and a random suffix.
+//
+// The suffix is derived from the random seed the engine sends with each Check request, so previews and updates
+// agree on the name. Older engines send no seed, in which case the suffix comes from a secure random source.
+func applyAutoName(res *resources.CustomResource, urn resource.URN, seed []byte, olds, news resource.PropertyMap) error {
+	if res.AutoName == nil {
+		return nil
+	}
+	key := resource.PropertyKey(res.AutoName.Property)
+	if hasValue(news, key) {
+		return nil
+	}
+	if old, ok := olds[key]; ok && !old.IsNull() {
+		news[key] = old
+		return nil
+	}
+
+	name, err := autoName(urn.Name().String(), seed, res.AutoName, maxNameLength(res))
+	if err != nil {
+		return err
+	}
+	// Logical names may not fit the pattern of the property, so fall back to the bare suffix.
+	if c := res.Constraints[res.AutoName.Property]; !c.Matches(name) {
+		if name, err = autoName("", seed, res.AutoName, maxNameLength(res)); err != nil {
+			return err
+		}
+		if !c.Matches(name) {
+			return fmt.Errorf("cannot generate a name for %s that matches the pattern %q", key, c.Pattern)
+		}
+	}
+	news[key] = resource.NewStringProperty(name)
+	return nil
+}
+
+// maxNameLength returns the maximum length of an auto-generated name, or zero if it is unlimited.
+func maxNameLength(res *resources.CustomResource) int {
+	if res.AutoName.MaxLength > 0 {
+		return res.AutoName.MaxLength
+	}
+	if c, ok := res.Constraints[res.AutoName.Property]; ok && c.MaxLength != nil {
+		return *c.MaxLength
+	}
+	return 0
+}
+
+// autoName generates a name of the form `<prefix>-<random suffix>`, truncating the prefix to fit maxLength. The
+// prefix is reduced to the characters of the suffix charset and dashes; without a prefix, the name is only the suffix.
+func autoName(prefix string, seed []byte, spec *resources.AutoName, maxLength int) (string, error) {
+	charset := spec.Charset
+	if charset == "" {
+		charset = defaultAutoNameCharset
+	}
+	suffixLength := spec.SuffixLength
+	if suffixLength <= 0 {
+		suffixLength = defaultAutoNameSuffixLength
+	}
+
+	prefix = sanitizePrefix(prefix, charset)
+	if maxLength > 0 {
+		if suffixLength > maxLength {
+			return "", fmt.Errorf("cannot generate a name with a %d character suffix within %d characters",
+				suffixLength, maxLength)
+		}
+		if runes := []rune(prefix); len(runes)+1+suffixLength > maxLength {
+			prefix = ""
+			if n := maxLength - 1 - suffixLength; n > 0 {
+				prefix = strings.TrimRight(string(runes[:n]), "-")
+			}
+		}
+	}
+	if prefix != "" {
+		prefix += "-"
+	}
+
+	name, err := resource.NewUniqueName(seed, prefix, suffixLength, 0, []rune(charset))
+	if err != nil {
+		return "", fmt.Errorf("generating name: %w", err)
+	}
+	return name, nil
+}
+
+// sanitizePrefix drops the characters of a prefix that are neither dashes nor in the charset, after trying their
+// lowercase form, and trims leading and trailing dashes.
+func sanitizePrefix(prefix, charset string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		switch {
+		case r == '-' || strings.ContainsRune(charset, r):
+			b.WriteRune(r)
+		case strings.ContainsRune(charset, unicode.ToLower(r)):
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+type bucketInputs struct {
+	Name string `pulumi:"name,optional"`
+}
+
+func bucket() *resources.CustomResource {
+	return &resources.CustomResource{
+		Inputs:   bucketInputs{},
+		Outputs:  bucketInputs{},
+		AutoName: &resources.AutoName{Property: "name"},
+		Create:   createWidget,
+	}
+}
+
+// checkName checks a Widget with the given logical name and random seed, and returns the name it is given.
+func checkName(t *testing.T, res *resources.CustomResource, name string, seed []byte,
+	olds, news map[string]interface{}) string {
+
+	t.Helper()
+	resp, err := serve(t, res).Check(context.Background(), &rpc.CheckRequest{
+		Urn:        string(resource.NewURN("test", "provider", "", widgetType, tokens.QName(name))),
+		Olds:       marshal(t, resource.NewPropertyMapFromMap(olds)),
+		News:       marshal(t, resource.NewPropertyMapFromMap(news)),
+		RandomSeed: seed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetFailures()) != 0 {
+		t.Fatalf("expected no failures, got\n%s", failureReasons(resp))
+	}
+	return checkedInputs(t, resp)["name"].StringValue()
+}
+
+func TestAutoName(t *testing.T) {
+	seed := []byte("seed")
+	name := checkName(t, bucket(), "logs", seed, nil, map[string]interface{}{})
+	if !regexp.MustCompile(`^logs-[a-z0-9]{7}$`).MatchString(name) {
+		t.Errorf("expected a name of the form logs-<suffix>, got %q", name)
+	}
+
+	// Previews and updates send the same seed, so they agree on the name.
+	if again := checkName(t, bucket(), "logs", seed, nil, map[string]interface{}{}); again != name {
+		t.Errorf("expected the same seed to generate %q, got %q", name, again)
+	}
+	if other := checkName(t, bucket(), "logs", []byte("other"), nil, map[string]interface{}{}); other == name {
+		t.Errorf("expected another seed to generate another name than %q", name)
+	}
+
+	// Existing resources keep their name, and explicit names win.
+	old := map[string]interface{}{"name": "logs-1234567"}
+	if kept := checkName(t, bucket(), "logs", seed, old, map[string]interface{}{}); kept != "logs-1234567" {
+		t.Errorf("expected the old name to be kept, got %q", kept)
+	}
+	explicit := map[string]interface{}{"name": "archive"}
+	if given := checkName(t, bucket(), "logs", seed, old, explicit); given != "archive" {
+		t.Errorf("expected the explicit name to be kept, got %q", given)
+	}
+}
+
+func TestAutoNameFitsConstraints(t *testing.T) {
+	// The prefix is truncated to the maximum length of the property.
+	res := bucket()
+	maxLength := 12
+	res.Constraints = map[string]resources.Constraint{"name": {MaxLength: &maxLength}}
+	if name := checkName(t, res, "access-logs", nil, nil, map[string]interface{}{}); len(name) != 12 ||
+		!strings.HasPrefix(name, "acce-") {
+		t.Errorf("expected a name of 12 characters starting with acce-, got %q", name)
+	}
+
+	// The prefix is dropped if the name would not match the pattern of the property.
+	res = bucket()
+	res.Constraints = map[string]resources.Constraint{"name": {Pattern: "^[a-z0-9]+$"}}
+	name := checkName(t, res, "logs", nil, nil, map[string]interface{}{})
+	if !regexp.MustCompile(`^[a-z0-9]{7}$`).MatchString(name) {
+		t.Errorf("expected a bare suffix, got %q", name)
+	}
+}
+
+func TestAutoNameCharset(t *testing.T) {
+	spec := &resources.AutoName{Property: "name", Charset: "abc", SuffixLength: 4}
+	name, err := autoName("My_Logs.Bucket", nil, spec, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^bc-[abc]{4}$`).MatchString(name) {
+		t.Errorf("expected the prefix and suffix to be limited to the charset, got %q", name)
+	}
+
+	if name, err = autoName("--Web Server--", nil, &resources.AutoName{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "webserver-") {
+		t.Errorf("expected the prefix to be sanitized to webserver, got %q", name)
+	}
+
+	if _, err = autoName("logs", nil, &resources.AutoName{SuffixLength: 8}, 6); err == nil {
+		t.Error("expected a suffix longer than the maximum length to be rejected")
+	}
+}
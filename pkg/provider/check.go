@@ -0,0 +1,341 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// applyDefaults fills in schema defaults for input properties that are missing from inputs. A value taken from the
+// first set environment variable listed in the property's DefaultInfo wins over its static Default.
+func applyDefaults(res *resources.CustomResource, inputs resource.PropertyMap) []*rpc.CheckFailure {
+	var failures []*rpc.CheckFailure
+	for _, name := range sortedKeys(res.Schema.InputProperties) {
+		key := resource.PropertyKey(name)
+		if _, ok := inputs[key]; ok {
+			continue
+		}
+
+		prop := res.Schema.InputProperties[name]
+		value, err := defaultValue(primitiveType(res, prop.TypeSpec), prop)
+		if err != nil {
+			failures = append(failures, &rpc.CheckFailure{Property: name, Reason: err.Error()})
+			continue
+		}
+		if value != nil {
+			inputs[key] = *value
+		}
+	}
+	return failures
+}
+
+// defaultValue returns the default value of a property of the given primitive type, or nil if it has none.
+func defaultValue(typ string, prop schema.PropertySpec) (*resource.PropertyValue, error) {
+	if prop.DefaultInfo != nil {
+		for _, env := range prop.DefaultInfo.Environment {
+			str, ok := os.LookupEnv(env)
+			if !ok || str == "" {
+				continue
+			}
+			value, err := parseDefault(typ, str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of environment variable %s, expected %s", str, env, typ)
+			}
+			return &value, nil
+		}
+	}
+	if prop.Default != nil {
+		value := resource.NewPropertyValue(prop.Default)
+		return &value, nil
+	}
+	return nil, nil
+}
+
+// primitiveType returns the type of a property, or the underlying type of the enum type it refers to.
+func primitiveType(res *resources.CustomResource, typ schema.TypeSpec) string {
+	if typ.Ref != "" {
+		if t, ok := res.Types[strings.TrimPrefix(typ.Ref, "#/types/")]; ok && len(t.Enum) > 0 {
+			return t.Type
+		}
+	}
+	return typ.Type
+}
+
+// parseDefault converts the string representation of a default value to a value of the given primitive type.
+func parseDefault(typ, str string) (resource.PropertyValue, error) {
+	switch typ {
+	case "boolean":
+		b, err := strconv.ParseBool(str)
+		return resource.NewBoolProperty(b), err
+	case "integer", "number":
+		n, err := strconv.ParseFloat(str, 64)
+		if err == nil && typ == "integer" && n != float64(int64(n)) {
+			err = strconv.ErrSyntax
+		}
+		return resource.NewNumberProperty(n), err
+	default:
+		return resource.NewStringProperty(str), nil
+	}
+}
+
+// inputValidator validates input values against the schema of a resource and collects check failures.
+type inputValidator struct {
+	res      *resources.CustomResource
+	failures []*rpc.CheckFailure
+}
+
+func (v *inputValidator) fail(path, format string, args ...interface{}) {
+	v.failures = append(v.failures, &rpc.CheckFailure{
+		Property: path,
+		Reason:   fmt.Sprintf(format, args...),
+	})
+}
+
+// validateRequired reports required input properties that are not set.
+func (v *inputValidator) validateRequired(inputs resource.PropertyMap) {
+	for _, name := range v.res.Schema.RequiredInputs {
+		if !hasValue(inputs, resource.PropertyKey(name)) {
+			v.fail(name, "missing required property %s", name)
+		}
+	}
+}
+
+// validateInputs validates all input properties of the resource.
+func (v *inputValidator) validateInputs(inputs resource.PropertyMap) {
+	v.validateProperties("", v.res.Schema.InputProperties, inputs, false)
+	v.validateConstraints(inputs)
+}
+
+// validateConstraints enforces the value and cross-field constraints declared by the resource.
+func (v *inputValidator) validateConstraints(inputs resource.PropertyMap) {
+	isSet := func(name string) bool {
+		value, ok := inputs[resource.PropertyKey(name)]
+		return ok && !value.IsNull()
+	}
+
+	names := make([]string, 0, len(v.res.Constraints))
+	for name := range v.res.Constraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checkedGroups := map[string]bool{}
+	for _, name := range names {
+		c := v.res.Constraints[name]
+
+		if len(c.ExactlyOneOf) > 0 {
+			group := append([]string(nil), c.ExactlyOneOf...)
+			sort.Strings(group)
+			if key := strings.Join(group, ","); !checkedGroups[key] {
+				checkedGroups[key] = true
+				count := 0
+				for _, other := range group {
+					if isSet(other) {
+						count++
+					}
+				}
+				if count != 1 {
+					v.fail(name, "exactly one of %s must be set", strings.Join(group, ", "))
+				}
+			}
+		}
+
+		if !isSet(name) {
+			continue
+		}
+		for _, other := range c.ConflictsWith {
+			if isSet(other) {
+				v.fail(name, "%s conflicts with %s, only one of them can be set", name, other)
+			}
+		}
+		for _, other := range c.RequiredWith {
+			if !isSet(other) {
+				v.fail(name, "%s requires %s to be set", name, other)
+			}
+		}
+		value := inputs[resource.PropertyKey(name)]
+		secret := value.IsSecret() || v.res.Schema.InputProperties[name].Secret
+		v.validateValueConstraint(name, c, value, secret)
+	}
+}
+
+// validateValueConstraint enforces ranges, lengths and patterns on a known property value. Failures for secret values
+// do not mention the value.
+func (v *inputValidator) validateValueConstraint(name string, c resources.Constraint, value resource.PropertyValue,
+	secret bool) {
+
+	value = unwrapSecret(value)
+
+	switch {
+	case value.IsNumber():
+		n := value.NumberValue()
+		if c.Min != nil && n < *c.Min {
+			v.fail(name, "%s must be at least %v%s", name, *c.Min, actual(secret, n))
+		}
+		if c.Max != nil && n > *c.Max {
+			v.fail(name, "%s must be at most %v%s", name, *c.Max, actual(secret, n))
+		}
+	case value.IsString():
+		str := value.StringValue()
+		v.validateLength(name, c, utf8.RuneCountInString(str), secret)
+		if !c.Matches(str) {
+			v.fail(name, "%s must match the regular expression %q%s", name, c.Pattern, actual(secret, strconv.Quote(str)))
+		}
+	case value.IsArray():
+		v.validateLength(name, c, len(value.ArrayValue()), secret)
+	}
+}
+
+func (v *inputValidator) validateLength(name string, c resources.Constraint, length int, secret bool) {
+	if c.MinLength != nil && length < *c.MinLength {
+		v.fail(name, "length of %s must be at least %d%s", name, *c.MinLength, actual(secret, length))
+	}
+	if c.MaxLength != nil && length > *c.MaxLength {
+		v.fail(name, "length of %s must be at most %d%s", name, *c.MaxLength, actual(secret, length))
+	}
+}
+
+// actual describes the offending value at the end of a failure message, unless it is secret.
+func actual(secret bool, value interface{}) string {
+	if secret {
+		return ""
+	}
+	return fmt.Sprintf(" but got %v", value)
+}
+
+func (v *inputValidator) validateProperties(prefix string, props map[string]schema.PropertySpec,
+	values resource.PropertyMap, secret bool) {
+
+	for _, name := range sortedKeys(props) {
+		if value, ok := values[resource.PropertyKey(name)]; ok {
+			v.validateValue(prefix+name, props[name].TypeSpec, value, secret || props[name].Secret)
+		}
+	}
+}
+
+// validateValue validates a value against its type. Values of secret properties and values inside secrets are secret
+// themselves, and are left out of failure messages.
+func (v *inputValidator) validateValue(path string, typ schema.TypeSpec, value resource.PropertyValue, secret bool) {
+	secret = secret || value.IsSecret()
+	value = unwrapSecret(value)
+	if value.IsComputed() || value.IsOutput() || value.IsNull() {
+		return
+	}
+	if typ.Ref != "" {
+		v.validateRef(path, typ.Ref, value, secret)
+		return
+	}
+
+	var ok bool
+	switch typ.Type {
+	case "string":
+		ok = value.IsString()
+	case "number":
+		ok = value.IsNumber()
+	case "integer":
+		if ok = value.IsNumber(); ok && value.NumberValue() != math.Trunc(value.NumberValue()) {
+			v.fail(path, "%s must be a whole number%s", path, actual(secret, value.NumberValue()))
+			return
+		}
+	case "boolean":
+		ok = value.IsBool()
+	case "array":
+		if ok = value.IsArray(); ok && typ.Items != nil {
+			for i, item := range value.ArrayValue() {
+				v.validateValue(fmt.Sprintf("%s[%d]", path, i), *typ.Items, item, secret)
+			}
+		}
+	case "object":
+		if ok = value.IsObject(); ok && typ.AdditionalProperties != nil {
+			obj := value.ObjectValue()
+			for _, key := range obj.StableKeys() {
+				v.validateValue(fmt.Sprintf("%s.%s", path, key), *typ.AdditionalProperties, obj[key], secret)
+			}
+		}
+	default:
+		return
+	}
+	if !ok {
+		v.fail(path, "%s must be of type %s, got %s", path, typ.Type, value.TypeString())
+	}
+}
+
+// validateRef validates a value of an auxiliary type: enum values must be one of the allowed values, objects are
+// validated property by property and other types by their underlying type.
+func (v *inputValidator) validateRef(path, ref string, value resource.PropertyValue, secret bool) {
+	const prefix = "#/types/"
+	if !strings.HasPrefix(ref, prefix) {
+		return
+	}
+	typ, ok := v.res.Types[strings.TrimPrefix(ref, prefix)]
+	if !ok {
+		return
+	}
+
+	if len(typ.Enum) > 0 {
+		allowed := make([]string, len(typ.Enum))
+		for i, e := range typ.Enum {
+			if resource.NewPropertyValue(e.Value).DeepEquals(value) {
+				return
+			}
+			allowed[i] = formatValue(resource.NewPropertyValue(e.Value))
+		}
+		if secret {
+			v.fail(path, "invalid value for property %s, allowed values are: %s", path, strings.Join(allowed, ", "))
+		} else {
+			v.fail(path, "invalid value %s for property %s, allowed values are: %s",
+				formatValue(value), path, strings.Join(allowed, ", "))
+		}
+		return
+	}
+
+	if typ.Type != "object" {
+		v.validateValue(path, schema.TypeSpec{Type: typ.Type}, value, secret)
+		return
+	}
+	if !value.IsObject() {
+		v.fail(path, "%s must be of type object, got %s", path, value.TypeString())
+		return
+	}
+	v.validateProperties(path+".", typ.Properties, value.ObjectValue(), secret)
+}
+
+// formatValue renders a primitive value for use in check failure messages.
+func formatValue(value resource.PropertyValue) string {
+	if value.IsString() {
+		return strconv.Quote(value.StringValue())
+	}
+	return fmt.Sprintf("%v", value.V)
+}
+
+func sortedKeys(props map[string]schema.PropertySpec) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,447 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+const widgetType = "xyz:index:Widget"
+
+func createWidget(context.Context, map[string]interface{}) (string, map[string]interface{}, error) {
+	return "widget", map[string]interface{}{}, nil
+}
+
+// widgetURN is the URN of the Widget resource in the tests.
+var widgetURN = string(resource.NewURN("test", "provider", "", widgetType, "w"))
+
+// serve registers the resource as a Widget and creates a provider for it.
+func serve(t *testing.T, res *resources.CustomResource) rpc.ResourceProviderServer {
+	t.Helper()
+	registry := resources.NewRegistry("xyz")
+	if err := registry.Register(widgetType, res); err != nil {
+		t.Fatal(err)
+	}
+	server, err := New(nil, Options{Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// check registers the resource as a Widget and checks the given inputs.
+func check(t *testing.T, res *resources.CustomResource, olds, news map[string]interface{}) *rpc.CheckResponse {
+	t.Helper()
+	resp, err := serve(t, res).Check(context.Background(), &rpc.CheckRequest{
+		Urn:  widgetURN,
+		Olds: marshal(t, resource.NewPropertyMapFromMap(olds)),
+		News: marshal(t, resource.NewPropertyMapFromMap(news)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// checkedInputs returns the inputs of a check response.
+func checkedInputs(t *testing.T, resp *rpc.CheckResponse) resource.PropertyMap {
+	t.Helper()
+	inputs, err := plugin.UnmarshalProperties(resp.GetInputs(), plugin.MarshalOptions{
+		KeepUnknowns: true, KeepSecrets: true, SkipNulls: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inputs
+}
+
+// failureReasons returns the reasons of all check failures, one per line, prefixed with the property.
+func failureReasons(resp *rpc.CheckResponse) string {
+	var reasons []string
+	for _, f := range resp.GetFailures() {
+		reasons = append(reasons, f.GetProperty()+": "+f.GetReason())
+	}
+	return strings.Join(reasons, "\n")
+}
+
+type color string
+
+func (color) EnumValues() []schema.EnumValueSpec {
+	return []schema.EnumValueSpec{{Name: "Red", Value: "red"}, {Name: "Blue", Value: "blue"}}
+}
+
+type paletteInputs struct {
+	Color  color `pulumi:"color,optional"`
+	Hidden color `pulumi:"hidden,optional,secret"`
+}
+
+func TestEnumValidation(t *testing.T) {
+	palette := func() *resources.CustomResource {
+		return &resources.CustomResource{Inputs: paletteInputs{}, Outputs: paletteInputs{}, Create: createWidget}
+	}
+
+	resp := check(t, palette(), nil, map[string]interface{}{"color": "red", "hidden": "blue"})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected allowed values to pass, got\n%s", failureReasons(resp))
+	}
+
+	resp = check(t, palette(), nil, map[string]interface{}{"color": "purple"})
+	expected := `color: invalid value "purple" for property color, allowed values are: "red", "blue"`
+	if reasons := failureReasons(resp); reasons != expected {
+		t.Errorf("expected the failure\n%s\ngot\n%s", expected, reasons)
+	}
+
+	// Secret values are not revealed, whether they are marked on the wire or declared secret in the schema.
+	resp = check(t, palette(), nil, map[string]interface{}{
+		"color":  &resource.Secret{Element: resource.NewStringProperty("hunter2")},
+		"hidden": "hunter2",
+	})
+	reasons := failureReasons(resp)
+	if len(resp.GetFailures()) != 2 || strings.Contains(reasons, "hunter2") {
+		t.Errorf("expected two failures without the secret values, got\n%s", reasons)
+	}
+}
+
+type lampInputs struct {
+	Watts  int   `pulumi:"watts,optional" default:"60"`
+	Shade  color `pulumi:"shade,optional" env:"XYZ_TEST_SHADE" default:"red"`
+	Dimmed bool  `pulumi:"dimmed,optional" env:"XYZ_TEST_DIMMED"`
+}
+
+func TestDefaults(t *testing.T) {
+	lamp := func() *resources.CustomResource {
+		return &resources.CustomResource{Inputs: lampInputs{}, Outputs: lampInputs{}, Create: createWidget}
+	}
+
+	resp := check(t, lamp(), nil, map[string]interface{}{})
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{"watts": 60, "shade": "red"})
+	if inputs := checkedInputs(t, resp); !inputs.DeepEquals(expected) {
+		t.Errorf("expected the static defaults %v, got %v", expected, inputs)
+	}
+
+	// Environment variables win over static defaults, and explicit values win over both.
+	t.Setenv("XYZ_TEST_SHADE", "blue")
+	t.Setenv("XYZ_TEST_DIMMED", "true")
+	resp = check(t, lamp(), nil, map[string]interface{}{"watts": 40})
+	expected = resource.NewPropertyMapFromMap(map[string]interface{}{"watts": 40, "shade": "blue", "dimmed": true})
+	if inputs := checkedInputs(t, resp); !inputs.DeepEquals(expected) {
+		t.Errorf("expected the environment defaults %v, got %v", expected, inputs)
+	}
+
+	t.Setenv("XYZ_TEST_SHADE", "green")
+	t.Setenv("XYZ_TEST_DIMMED", "maybe")
+	resp = check(t, lamp(), nil, map[string]interface{}{})
+	expectedFailures := "dimmed: invalid value \"maybe\" of environment variable XYZ_TEST_DIMMED, expected boolean\n" +
+		"shade: invalid value \"green\" for property shade, allowed values are: \"red\", \"blue\""
+	if reasons := failureReasons(resp); reasons != expectedFailures {
+		t.Errorf("expected the failures\n%s\ngot\n%s", expectedFailures, reasons)
+	}
+}
+
+type accountInputs struct {
+	Name     string `pulumi:"name" pattern:"^[a-z]+$" maxLength:"8"`
+	Seats    int    `pulumi:"seats" min:"1" max:"10"`
+	Password string `pulumi:"password,optional,secret" minLength:"8" conflictsWith:"key"`
+	Key      string `pulumi:"key,optional" pattern:"^k-"`
+}
+
+func TestConstraints(t *testing.T) {
+	account := func() *resources.CustomResource {
+		return &resources.CustomResource{Inputs: accountInputs{}, Outputs: accountInputs{}, Create: createWidget}
+	}
+
+	resp := check(t, account(), nil, map[string]interface{}{"name": "alice", "seats": 3, "password": "correcthorse"})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected valid inputs to pass, got\n%s", failureReasons(resp))
+	}
+
+	resp = check(t, account(), nil, map[string]interface{}{
+		"name":     "Mallory-1",
+		"seats":    11,
+		"password": "hunter2",
+		"key":      &resource.Secret{Element: resource.NewStringProperty("hunter3")},
+	})
+	expected := []string{
+		`key: key must match the regular expression "^k-"`,
+		`name: length of name must be at most 8 but got 9`,
+		`name: name must match the regular expression "^[a-z]+$" but got "Mallory-1"`,
+		`password: password conflicts with key, only one of them can be set`,
+		`password: length of password must be at least 8`,
+		`seats: seats must be at most 10 but got 11`,
+	}
+	if reasons := failureReasons(resp); reasons != strings.Join(expected, "\n") {
+		t.Errorf("expected the failures\n%s\ngot\n%s", strings.Join(expected, "\n"), reasons)
+	}
+}
+
+type shelfInputs struct {
+	Label   string            `pulumi:"label,optional"`
+	Count   int               `pulumi:"count,optional"`
+	Visible bool              `pulumi:"visible,optional"`
+	Books   []string          `pulumi:"books,optional"`
+	Tags    map[string]string `pulumi:"tags,optional"`
+	Lamp    *lampInputs       `pulumi:"lamp,optional"`
+}
+
+func TestTypes(t *testing.T) {
+	shelf := func() *resources.CustomResource {
+		return &resources.CustomResource{Inputs: shelfInputs{}, Outputs: shelfInputs{}, Create: createWidget}
+	}
+
+	resp := check(t, shelf(), nil, map[string]interface{}{
+		"label":   "fiction",
+		"count":   3,
+		"visible": true,
+		"books":   []interface{}{"Dune", resource.Computed{}},
+		"tags":    map[string]interface{}{"genre": "sf"},
+		"lamp":    map[string]interface{}{"watts": 40},
+	})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected values of the declared types to pass, got\n%s", failureReasons(resp))
+	}
+
+	resp = check(t, shelf(), nil, map[string]interface{}{
+		"label":   7,
+		"count":   &resource.Secret{Element: resource.NewNumberProperty(2.5)},
+		"visible": "yes",
+		"books":   []interface{}{"Dune", 42},
+		"tags":    map[string]interface{}{"genre": false},
+		"lamp":    "bright",
+	})
+	expected := []string{
+		`books[1]: books[1] must be of type string, got number`,
+		`count: count must be a whole number`,
+		`label: label must be of type string, got number`,
+		`lamp: lamp must be of type object, got string`,
+		`tags.genre: tags.genre must be of type string, got bool`,
+		`visible: visible must be of type boolean, got string`,
+	}
+	if reasons := failureReasons(resp); reasons != strings.Join(expected, "\n") {
+		t.Errorf("expected the failures\n%s\ngot\n%s", strings.Join(expected, "\n"), reasons)
+	}
+}
+
+func TestInvalidPatternsAreRejected(t *testing.T) {
+	err := resources.NewRegistry("xyz").Register(widgetType, &resources.CustomResource{
+		Inputs:      paletteInputs{},
+		Outputs:     paletteInputs{},
+		Constraints: map[string]resources.Constraint{"color": {Pattern: "r("}},
+		Create:      createWidget,
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected the invalid pattern to be rejected, got %v", err)
+	}
+}
+
+type userInputs struct {
+	Name string `pulumi:"name"`
+}
+
+func TestCheckHook(t *testing.T) {
+	var seenOlds map[string]interface{}
+	user := func() *resources.CustomResource {
+		return &resources.CustomResource{
+			Inputs:  userInputs{},
+			Outputs: userInputs{},
+			Create:  createWidget,
+			Check: func(_ context.Context, olds, news map[string]interface{}) (
+				map[string]interface{}, []resources.CheckFailure, error) {
+
+				seenOlds = olds
+				name := strings.ToLower(strings.TrimSpace(news["name"].(string)))
+				if name == "root" {
+					return nil, []resources.CheckFailure{{Property: "name", Reason: "name is reserved"}}, nil
+				}
+				return map[string]interface{}{"name": name}, nil, nil
+			},
+		}
+	}
+
+	resp := check(t, user(), map[string]interface{}{"name": "alice"}, map[string]interface{}{"name": " Bob "})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected no failures, got\n%s", failureReasons(resp))
+	}
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{"name": "bob"})
+	if inputs := checkedInputs(t, resp); !inputs.DeepEquals(expected) {
+		t.Errorf("expected the hook to normalize the inputs to %v, got %v", expected, inputs)
+	}
+	if seenOlds["name"] != "alice" {
+		t.Errorf("expected the hook to receive the old inputs, got %v", seenOlds)
+	}
+
+	// Failures are reported, and inputs are kept if the hook returns none.
+	resp = check(t, user(), nil, map[string]interface{}{"name": "Root"})
+	if reasons := failureReasons(resp); reasons != "name: name is reserved" {
+		t.Errorf("expected the failure of the hook, got\n%s", reasons)
+	}
+	if name := checkedInputs(t, resp)["name"]; !name.IsString() || name.StringValue() != "Root" {
+		t.Errorf("expected the inputs to be kept, got %v", name)
+	}
+}
+
+func TestRenamedTypeWarns(t *testing.T) {
+	registry := resources.NewRegistry("xyz")
+	registry.MustRegister("xyz:gadgets:Widget", &resources.CustomResource{
+		Aliases: []string{widgetType},
+		Inputs:  userInputs{},
+		Outputs: userInputs{},
+		Create:  createWidget,
+	})
+	logs := &logRecorder{}
+	server, err := New(nil, Options{Registry: registry, Logger: logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.Check(context.Background(), &rpc.CheckRequest{
+		Urn:  widgetURN,
+		News: marshal(t, resource.NewPropertyMapFromMap(map[string]interface{}{"name": "alice"})),
+	})
+	if err != nil {
+		t.Fatalf("expected the old token to be accepted, got %v", err)
+	}
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected no failures, got\n%s", failureReasons(resp))
+	}
+	expected := `warning: resource type "xyz:index:Widget" has been renamed to "xyz:gadgets:Widget", ` +
+		`please update your program`
+	if len(*logs) != 1 || (*logs)[0] != expected {
+		t.Errorf("expected the warning %q, got %v", expected, *logs)
+	}
+}
+
+type gadgetInputs struct {
+	Name  string `pulumi:"name,optional"`
+	Size  int    `pulumi:"size,optional" deprecated:"Use width instead."`
+	Width int    `pulumi:"width,optional"`
+}
+
+func TestDeprecationsWarn(t *testing.T) {
+	registry := resources.NewRegistry("xyz")
+	registry.MustRegister(widgetType, &resources.CustomResource{
+		DeprecationMessage: "Use xyz:index:Gadget instead.",
+		Inputs:             gadgetInputs{},
+		Outputs:            gadgetInputs{},
+		Create:             createWidget,
+	})
+	logs := &logRecorder{}
+	server, err := New(nil, Options{Registry: registry, Logger: logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(news map[string]interface{}) {
+		if _, err := server.Check(context.Background(), &rpc.CheckRequest{
+			Urn:  widgetURN,
+			News: marshal(t, resource.NewPropertyMapFromMap(news)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deprecated properties only warn when they are set.
+	check(map[string]interface{}{"width": 3})
+	expected := []string{`warning: resource type "xyz:index:Widget" is deprecated: Use xyz:index:Gadget instead.`}
+	if !reflect.DeepEqual([]string(*logs), expected) {
+		t.Errorf("expected the warnings %v, got %v", expected, *logs)
+	}
+
+	// Each request warns once per deprecation.
+	*logs = nil
+	check(map[string]interface{}{"size": 3})
+	check(map[string]interface{}{"size": 3})
+	warning := []string{
+		`warning: resource type "xyz:index:Widget" is deprecated: Use xyz:index:Gadget instead.`,
+		`warning: property "size" of "xyz:index:Widget" is deprecated: Use width instead.`,
+	}
+	expected = append(warning, warning...)
+	if !reflect.DeepEqual([]string(*logs), expected) {
+		t.Errorf("expected the warnings %v, got %v", expected, *logs)
+	}
+
+	w := &warnings{}
+	w.add("%s is deprecated", "size")
+	w.add("%s is deprecated", "size")
+	if len(w.messages) != 1 {
+		t.Errorf("expected repeated warnings to be reported once, got %v", w.messages)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	registry := resources.NewRegistry("xyz")
+	registry.MustRegister(widgetType, &resources.CustomResource{
+		Inputs: userInputs{}, Outputs: userInputs{}, Create: createWidget,
+	})
+	server, err := New(nil, Options{Registry: registry, Config: schema.ConfigSpec{
+		Variables: map[string]schema.PropertySpec{
+			"region":  {TypeSpec: schema.TypeSpec{Type: "string"}},
+			"retries": {TypeSpec: schema.TypeSpec{Type: "integer"}, Default: 3.0},
+		},
+		Required: []string{"region"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConfig := func(news map[string]interface{}) *rpc.CheckResponse {
+		resp, err := server.CheckConfig(context.Background(), &rpc.CheckRequest{
+			News: marshal(t, resource.NewPropertyMapFromMap(news)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := checkConfig(map[string]interface{}{"region": "eu-west-1"})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected no failures, got\n%s", failureReasons(resp))
+	}
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{"region": "eu-west-1", "retries": 3})
+	if inputs := checkedInputs(t, resp); !inputs.DeepEquals(expected) {
+		t.Errorf("expected the configuration %v, got %v", expected, inputs)
+	}
+
+	resp = checkConfig(map[string]interface{}{"retries": 5})
+	if reasons := failureReasons(resp); reasons != "region: missing required property region" {
+		t.Errorf("expected the missing region to be reported, got\n%s", reasons)
+	}
+
+	// Values set on the command line arrive as strings.
+	resp = checkConfig(map[string]interface{}{"region": "eu-west-1", "retries": "5"})
+	if len(resp.GetFailures()) != 0 {
+		t.Errorf("expected no failures, got\n%s", failureReasons(resp))
+	}
+	if retries := checkedInputs(t, resp)["retries"]; !retries.IsNumber() || retries.NumberValue() != 5 {
+		t.Errorf("expected the retries to be parsed to 5, got %v", retries)
+	}
+	resp = checkConfig(map[string]interface{}{"region": "eu-west-1", "retries": "many"})
+	if reasons := failureReasons(resp); reasons != "retries: retries must be of type integer, got string" {
+		t.Errorf("expected the invalid retries to be reported, got\n%s", reasons)
+	}
+
+	resp = check(t, &resources.CustomResource{Inputs: userInputs{}, Outputs: userInputs{}, Create: createWidget},
+		nil, map[string]interface{}{})
+	if reasons := failureReasons(resp); reasons != "name: missing required property name" {
+		t.Errorf("expected the missing name to be reported, got\n%s", reasons)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// changedInputs returns the sorted names of input properties whose values differ between the old state and the new
+// inputs.
+func changedInputs(res *resources.CustomResource, olds, news resource.PropertyMap) []string {
+	names := map[string]bool{}
+	for name := range res.Schema.InputProperties {
+		names[name] = true
+	}
+	for key := range news {
+		names[string(key)] = true
+	}
+
+	var changed []string
+	for name := range names {
+		key := resource.PropertyKey(name)
+		if !unwrapSecret(olds[key]).DeepEquals(unwrapSecret(news[key])) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// withoutIgnored removes the properties listed in ignoreChanges from changed.
+func withoutIgnored(changed, ignoreChanges []string) []string {
+	ignored := map[string]bool{}
+	for _, name := range ignoreChanges {
+		ignored[name] = true
+	}
+	var result []string
+	for _, name := range changed {
+		if !ignored[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// replaceKinds maps each kind of property change to its counterpart that replaces the resource.
+var replaceKinds = map[rpc.PropertyDiff_Kind]rpc.PropertyDiff_Kind{
+	rpc.PropertyDiff_ADD:    rpc.PropertyDiff_ADD_REPLACE,
+	rpc.PropertyDiff_DELETE: rpc.PropertyDiff_DELETE_REPLACE,
+	rpc.PropertyDiff_UPDATE: rpc.PropertyDiff_UPDATE_REPLACE,
+}
+
+// detailedDiff describes how each changed property differs between the old state and the new inputs.
+func detailedDiff(changed []string, replaces []string, olds, news resource.PropertyMap) map[string]*rpc.PropertyDiff {
+	replaced := map[string]bool{}
+	for _, name := range replaces {
+		replaced[name] = true
+	}
+
+	diff := map[string]*rpc.PropertyDiff{}
+	for _, name := range changed {
+		key := resource.PropertyKey(name)
+		var kind rpc.PropertyDiff_Kind
+		switch {
+		case !hasValue(olds, key):
+			kind = rpc.PropertyDiff_ADD
+		case !hasValue(news, key):
+			kind = rpc.PropertyDiff_DELETE
+		default:
+			kind = rpc.PropertyDiff_UPDATE
+		}
+		if replaced[name] {
+			kind = replaceKinds[kind]
+		}
+		diff[name] = &rpc.PropertyDiff{Kind: kind}
+	}
+	return diff
+}
+
+func hasValue(props resource.PropertyMap, key resource.PropertyKey) bool {
+	value, ok := props[key]
+	return ok && !value.IsNull()
+}
+
+// unwrapSecret returns the value inside a secret. Secrets may be nested, e.g. when a secret is passed to a secret
+// input.
+func unwrapSecret(value resource.PropertyValue) resource.PropertyValue {
+	for value.IsSecret() {
+		value = value.SecretValue().Element
+	}
+	return value
+}
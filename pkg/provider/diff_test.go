@@ -0,0 +1,99 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// diff registers the resource as a Widget and diffs the old state against the new inputs.
+func diff(t *testing.T, res *resources.CustomResource, olds, news map[string]interface{}) *rpc.DiffResponse {
+	t.Helper()
+	resp, err := serve(t, res).Diff(context.Background(), &rpc.DiffRequest{
+		Id:   "widget",
+		Urn:  widgetURN,
+		Olds: marshal(t, resource.NewPropertyMapFromMap(olds)),
+		News: marshal(t, resource.NewPropertyMapFromMap(news)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// diffKinds returns the kind of each property change of a detailed diff.
+func diffKinds(detailed map[string]*rpc.PropertyDiff) map[string]rpc.PropertyDiff_Kind {
+	kinds := map[string]rpc.PropertyDiff_Kind{}
+	for name, d := range detailed {
+		kinds[name] = d.GetKind()
+	}
+	return kinds
+}
+
+func TestDiffHook(t *testing.T) {
+	user := func() *resources.CustomResource {
+		return &resources.CustomResource{
+			Inputs:  userInputs{},
+			Outputs: userInputs{},
+			Create:  createWidget,
+			Diff: func(_ context.Context, olds, news map[string]interface{}) ([]string, error) {
+				if strings.EqualFold(olds["name"].(string), news["name"].(string)) {
+					return nil, nil
+				}
+				return []string{"name"}, nil
+			},
+		}
+	}
+
+	resp := diff(t, user(), map[string]interface{}{"name": "Alice"}, map[string]interface{}{"name": "alice"})
+	if resp.GetChanges() != rpc.DiffResponse_DIFF_NONE {
+		t.Errorf("expected names that only differ in case to be unchanged, got %v", resp.GetDiffs())
+	}
+
+	resp = diff(t, user(), map[string]interface{}{"name": "Alice"}, map[string]interface{}{"name": "bob"})
+	if resp.GetChanges() != rpc.DiffResponse_DIFF_SOME || !reflect.DeepEqual(resp.GetDiffs(), []string{"name"}) {
+		t.Errorf("expected the name to change, got %v", resp.GetDiffs())
+	}
+	expected := map[string]rpc.PropertyDiff_Kind{"name": rpc.PropertyDiff_UPDATE_REPLACE}
+	if kinds := diffKinds(resp.GetDetailedDiff()); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected the detailed diff %v, got %v", expected, kinds)
+	}
+}
+
+func TestDetailedDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"a": 1, "b": 1, "c": 1, "d": 1})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"b": 2, "c": 2, "e": 1, "f": 1})
+	changed := []string{"a", "b", "c", "d", "e", "f"}
+	replaces := []string{"a", "c", "f"}
+
+	expected := map[string]rpc.PropertyDiff_Kind{
+		"a": rpc.PropertyDiff_DELETE_REPLACE,
+		"b": rpc.PropertyDiff_UPDATE,
+		"c": rpc.PropertyDiff_UPDATE_REPLACE,
+		"d": rpc.PropertyDiff_DELETE,
+		"e": rpc.PropertyDiff_ADD,
+		"f": rpc.PropertyDiff_ADD_REPLACE,
+	}
+	if kinds := diffKinds(detailedDiff(changed, replaces, olds, news)); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected the detailed diff %v, got %v", expected, kinds)
+	}
+}
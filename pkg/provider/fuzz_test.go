@@ -0,0 +1,227 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// generator turns fuzzer input into property maps. Keys are mostly property names of the resource under test, so
+// that values reach the type checks and resource functions instead of failing as unknown properties.
+type generator struct {
+	data  []byte
+	names []string
+}
+
+func (g *generator) next() byte {
+	if len(g.data) == 0 {
+		return 0
+	}
+	b := g.data[0]
+	g.data = g.data[1:]
+	return b
+}
+
+func (g *generator) propertyMap(depth int) resource.PropertyMap {
+	props := resource.PropertyMap{}
+	for n := int(g.next() % 5); n > 0; n-- {
+		props[g.key()] = g.value(depth)
+	}
+	return props
+}
+
+func (g *generator) key() resource.PropertyKey {
+	if b := int(g.next()); b < 224 && len(g.names) > 0 {
+		return resource.PropertyKey(g.names[b%len(g.names)])
+	}
+	return resource.PropertyKey(g.string())
+}
+
+func (g *generator) string() string {
+	n := int(g.next() % 16)
+	if n > len(g.data) {
+		n = len(g.data)
+	}
+	s := strings.ToValidUTF8(string(g.data[:n]), "?")
+	g.data = g.data[n:]
+	return s
+}
+
+func (g *generator) value(depth int) resource.PropertyValue {
+	kinds := byte(8)
+	if depth >= 3 {
+		kinds = 4
+	}
+	switch g.next() % kinds {
+	case 0:
+		return resource.NewNullProperty()
+	case 1:
+		return resource.NewBoolProperty(g.next()%2 == 0)
+	case 2:
+		// Numbers stay small, since resources may allocate memory proportional to their inputs.
+		return resource.NewNumberProperty(float64(int8(g.next())) / float64(g.next()%4+1))
+	case 3:
+		return resource.NewStringProperty(g.string())
+	case 4:
+		var items []resource.PropertyValue
+		for n := int(g.next() % 4); n > 0; n-- {
+			items = append(items, g.value(depth+1))
+		}
+		return resource.NewArrayProperty(items)
+	case 5:
+		return resource.NewObjectProperty(g.propertyMap(depth + 1))
+	case 6:
+		return resource.MakeSecret(g.value(depth + 1))
+	default:
+		return resource.MakeComputed(resource.NewStringProperty(""))
+	}
+}
+
+// fuzzTarget picks the resource under test from the first byte of the input.
+func fuzzTarget(t *testing.T, data []byte) (rpc.ResourceProviderServer, *resources.CustomResource, resource.URN,
+	*generator) {
+
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	server, err := New(nil, Options{Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &generator{data: data}
+	tokenList := registry.Tokens()
+	token := tokenList[int(g.next())%len(tokenList)]
+	res, _, _ := registry.Lookup(token)
+	for name := range res.Schema.InputProperties {
+		g.names = append(g.names, name)
+	}
+	for name := range res.Schema.Properties {
+		g.names = append(g.names, name)
+	}
+	g.names = append(g.names, resources.StateVersionKey)
+	sort.Strings(g.names)
+
+	urn := resource.NewURN("test", "fuzz", "", tokens.Type(token), "res")
+	return server, res, urn, g
+}
+
+func marshal(t *testing.T, props resource.PropertyMap) *structpb.Struct {
+	s, err := plugin.MarshalProperties(props, plugin.MarshalOptions{KeepUnknowns: true, KeepSecrets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// assertRoundTrip checks that marshaled outputs are unchanged by unmarshaling and marshaling them again.
+func assertRoundTrip(t *testing.T, outputs *structpb.Struct) {
+	opts := plugin.MarshalOptions{KeepUnknowns: true, KeepSecrets: true, SkipNulls: true}
+	props, err := plugin.UnmarshalProperties(outputs, opts)
+	if err != nil {
+		t.Fatalf("unmarshaling outputs: %v", err)
+	}
+	again, err := plugin.MarshalProperties(props, opts)
+	if err != nil {
+		t.Fatalf("marshaling outputs: %v", err)
+	}
+	if !proto.Equal(outputs, again) {
+		t.Errorf("outputs changed in a round trip: %v became %v", outputs, again)
+	}
+}
+
+// FuzzCheckCreate checks random inputs and, if they are valid, diffs, creates and reads the resource.
+func FuzzCheckCreate(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 8, 1})
+	f.Add([]byte{0, 2, 0, 2, 12, 1, 1, 3, 4, 'a', 'b', 'c', 'd'})
+	f.Add([]byte{0, 1, 0, 6, 2, 20, 2})
+	f.Add([]byte{0, 1, 0, 4, 3, 2, 1, 0, 3, 1, 'x'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		server, res, urn, g := fuzzTarget(t, data)
+		news := g.propertyMap(0)
+		olds := g.propertyMap(0)
+		ctx := context.Background()
+
+		checked, err := server.Check(ctx, &rpc.CheckRequest{
+			Urn: string(urn), Olds: marshal(t, olds), News: marshal(t, news),
+		})
+		if err != nil || len(checked.GetFailures()) > 0 {
+			return
+		}
+		if _, err := server.Diff(ctx, &rpc.DiffRequest{
+			Id: "id", Urn: string(urn), Olds: marshal(t, olds), News: checked.GetInputs(),
+		}); err != nil {
+			return
+		}
+
+		// The engine only creates resources from known inputs.
+		inputs, err := plugin.UnmarshalProperties(checked.GetInputs(), plugin.MarshalOptions{KeepUnknowns: true})
+		if err != nil {
+			t.Fatalf("unmarshaling checked inputs: %v", err)
+		}
+		if inputs.ContainsUnknowns() {
+			return
+		}
+		created, err := server.Create(ctx, &rpc.CreateRequest{Urn: string(urn), Properties: checked.GetInputs()})
+		if err != nil {
+			return
+		}
+		assertRoundTrip(t, created.GetProperties())
+
+		read, err := server.Read(ctx, &rpc.ReadRequest{
+			Id: created.GetId(), Urn: string(urn), Properties: created.GetProperties(),
+		})
+		if err != nil {
+			t.Fatalf("reading a created %s: %v", urn.Type(), err)
+		}
+		assertRoundTrip(t, read.GetProperties())
+		if res.Read == nil && !proto.Equal(read.GetProperties(), created.GetProperties()) {
+			t.Errorf("read changed the state %v to %v", created.GetProperties(), read.GetProperties())
+		}
+	})
+}
+
+// FuzzState feeds random stored state, as it may have been written by other provider versions, to Diff, Read and
+// Delete.
+func FuzzState(f *testing.F) {
+	f.Add([]byte{0, 2, 0, 2, 8, 1, 1, 3, 4, 'a', 'b', 'c', 'd', 1, 0, 2, 8, 1})
+	f.Add([]byte{0, 1, 2, 2, 2, 1, 1, 0, 2, 9, 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		server, _, urn, g := fuzzTarget(t, data)
+		state := marshal(t, g.propertyMap(0))
+		news := marshal(t, g.propertyMap(0))
+		ctx := context.Background()
+
+		_, _ = server.Diff(ctx, &rpc.DiffRequest{Id: "id", Urn: string(urn), Olds: state, News: news})
+		if read, err := server.Read(ctx, &rpc.ReadRequest{Id: "id", Urn: string(urn), Properties: state}); err == nil {
+			assertRoundTrip(t, read.GetProperties())
+		}
+		_, _ = server.Delete(ctx, &rpc.DeleteRequest{Id: "id", Urn: string(urn), Properties: state})
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/engine"
+	lt "github.com/pulumi/pulumi/pkg/v3/engine/lifecycletest"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/v3/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/v3/resource/provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/display"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/result"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+	"google.golang.org/grpc"
+)
+
+const randomStringType = "xyz:index:RandomString"
+
+// pluginProvider is the provider served over gRPC in-process, as the engine would load it from a plugin binary.
+type pluginProvider struct {
+	plugin.Provider
+
+	conn *grpc.ClientConn
+	host *provider.HostClient
+	stop chan bool
+}
+
+func (p *pluginProvider) Close() error {
+	err := p.conn.Close()
+	_ = p.host.Close()
+	go func() { p.stop <- true }()
+	return err
+}
+
+// loadProvider serves the provider in-process and connects the engine's plugin client to it.
+func loadProvider(host plugin.Host) (plugin.Provider, error) {
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		return nil, err
+	}
+	hostClient, err := provider.NewHostClient(host.ServerAddr())
+	if err != nil {
+		return nil, err
+	}
+	server, err := New(hostClient, Options{Registry: registry, Metadata: resources.Metadata{Version: "1.0.0"}})
+	if err != nil {
+		return nil, err
+	}
+
+	stop := make(chan bool)
+	port, _, err := rpcutil.Serve(0, stop, []func(*grpc.Server) error{
+		func(srv *grpc.Server) error {
+			rpc.RegisterResourceProviderServer(srv, server)
+			return nil
+		},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%v", port), grpc.WithInsecure(), rpcutil.GrpcChannelOptions())
+	if err != nil {
+		go func() { stop <- true }()
+		return nil, err
+	}
+	client := plugin.NewProviderWithClient(nil, "xyz", rpc.NewResourceProviderClient(conn), false)
+	return &pluginProvider{Provider: client, conn: conn, host: hostClient, stop: stop}, nil
+}
+
+// newPlan creates a test plan running the given program against the provider.
+func newPlan(t *testing.T, program deploytest.ProgramFunc) *lt.TestPlan {
+	stubPlugin(t)
+	loader := deploytest.NewProviderLoaderWithHost("xyz", semver.MustParse("1.0.0"), loadProvider)
+	host := deploytest.NewPluginHost(nil, nil, deploytest.NewLanguageRuntime(program), loader)
+	return &lt.TestPlan{Options: engine.UpdateOptions{Host: host}}
+}
+
+// stubPlugin puts an empty plugin binary on the PATH. The engine downloads the plugins of resources in the old state
+// unless it finds them installed; with the stub in place it loads the provider from the test host instead.
+func stubPlugin(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "pulumi-resource-xyz"), nil, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// expectOps returns a validation function that checks the successful operations on RandomString resources.
+func expectOps(t *testing.T, expected ...display.StepOp) lt.ValidateFunc {
+	return func(_ workspace.Project, _ deploy.Target, entries engine.JournalEntries, _ []engine.Event,
+		res result.Result) result.Result {
+
+		var ops []display.StepOp
+		for _, entry := range entries {
+			if entry.Kind == engine.JournalEntrySuccess && entry.Step.URN().Type() == randomStringType {
+				ops = append(ops, entry.Step.Op())
+			}
+		}
+		if !reflect.DeepEqual(ops, expected) {
+			t.Errorf("expected operations %v, got %v", expected, ops)
+		}
+		return res
+	}
+}
+
+// randomStrings returns the RandomString resources of a snapshot.
+func randomStrings(snap *deploy.Snapshot) []*resource.State {
+	var states []*resource.State
+	for _, state := range snap.Resources {
+		if state.Type == randomStringType {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+func TestRandomStringEngineLifecycle(t *testing.T) {
+	length := 8
+	plan := newPlan(t, func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource(randomStringType, "str", true, deploytest.ResourceOptions{
+			Inputs: resource.NewPropertyMapFromMap(map[string]interface{}{"length": length}),
+		})
+		return err
+	})
+
+	// Create the resource, then check that an unchanged program and a refresh leave it alone.
+	plan.Steps = []lt.TestStep{
+		{Op: engine.Update, Validate: expectOps(t, deploy.OpCreate)},
+		{Op: engine.Update, Validate: expectOps(t, deploy.OpSame)},
+		{Op: engine.Refresh, Validate: expectOps(t, deploy.OpRefresh)},
+	}
+	snap := plan.Run(t, nil)
+	states := randomStrings(snap)
+	if len(states) != 1 {
+		t.Fatalf("expected one RandomString, got %d", len(states))
+	}
+	result := states[0].Outputs["result"]
+	if !result.IsString() || len(result.StringValue()) != 8 {
+		t.Fatalf("expected a result of 8 characters, got %v", result)
+	}
+
+	// RandomString cannot be updated in place, so changing the length replaces it.
+	length = 12
+	plan.Steps = []lt.TestStep{{
+		Op:       engine.Update,
+		Validate: expectOps(t, deploy.OpCreateReplacement, deploy.OpReplace, deploy.OpDeleteReplaced),
+	}}
+	snap = plan.Run(t, snap)
+	states = randomStrings(snap)
+	if len(states) != 1 || states[0].ID != "12" {
+		t.Fatalf("expected the replacement to have ID 12, got %v", states)
+	}
+	if s := states[0].Outputs["result"].StringValue(); len(s) != 12 {
+		t.Errorf("expected a result of 12 characters, got %q", s)
+	}
+
+	// Refreshing keeps the stored value, since there is nothing to read it from.
+	plan.Steps = []lt.TestStep{{Op: engine.Refresh, Validate: expectOps(t, deploy.OpRefresh)}}
+	refreshed := randomStrings(plan.Run(t, snap))
+	if len(refreshed) != 1 || !refreshed[0].Outputs.DeepEquals(states[0].Outputs) {
+		t.Errorf("expected refresh to keep outputs %v, got %v", states[0].Outputs, refreshed)
+	}
+
+	plan.Steps = []lt.TestStep{{Op: engine.Destroy, Validate: expectOps(t, deploy.OpDelete)}}
+	snap = plan.Run(t, snap)
+	if states := randomStrings(snap); len(states) != 0 {
+		t.Errorf("expected destroy to delete the RandomString, got %v", states)
+	}
+}
+
+func TestRandomStringImport(t *testing.T) {
+	plan := newPlan(t, func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource(randomStringType, "imported", true, deploytest.ResourceOptions{
+			Inputs:   resource.NewPropertyMapFromMap(map[string]interface{}{"length": 8}),
+			ImportID: "8",
+		})
+		return err
+	})
+
+	// The random value of an existing string cannot be read back, so RandomString does not support import.
+	plan.Steps = []lt.TestStep{{
+		Op:            engine.Update,
+		ExpectFailure: true,
+		Validate: func(_ workspace.Project, _ deploy.Target, entries engine.JournalEntries, _ []engine.Event,
+			res result.Result) result.Result {
+
+			for _, entry := range entries {
+				if entry.Kind == engine.JournalEntrySuccess && entry.Step.URN().Type() == randomStringType {
+					t.Errorf("expected no RandomString to be imported, got %v", entry.Step.Op())
+				}
+			}
+			return res
+		},
+	}}
+	plan.Run(t, nil)
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/pkg/v3/resource/provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// Options configures a provider created with New.
+type Options struct {
+	// Resources implemented by the provider. The name of the provider is the package of the registry. Required.
+	Registry *resources.Registry
+	// Metadata of the package, including the provider version. Optional.
+	Metadata resources.Metadata
+	// Schema of the provider configuration. Check validates configuration values against it, and resource functions
+	// receive the configured values through resources.Config. Optional.
+	Config schema.ConfigSpec
+	// Middlewares wrap the provider, e.g. to add logging or tracing to every request. The first middleware is the
+	// outermost one. Optional.
+	Middlewares []Middleware
+	// Logger receives warnings about resources. Defaults to the engine's log. Optional.
+	Logger Logger
+	// OutputValidation checks the outputs of resource functions against the resource schema. Outputs are not
+	// checked by default. Optional.
+	OutputValidation OutputValidation
+}
+
+// Middleware wraps a provider server with additional behavior.
+type Middleware func(rpc.ResourceProviderServer) rpc.ResourceProviderServer
+
+// Logger sends diagnostic messages about a resource to the user. *provider.HostClient implements Logger.
+type Logger interface {
+	Log(ctx context.Context, sev diag.Severity, urn resource.URN, msg string) error
+}
+
+// New creates a provider that implements the resources of the registry. The host is used to communicate with the
+// engine and may be nil if a Logger is given or logging is not needed.
+func New(host *provider.HostClient, opts Options) (rpc.ResourceProviderServer, error) {
+	if opts.Registry == nil {
+		return nil, errors.New("a resource registry is required")
+	}
+
+	logger := opts.Logger
+	if logger == nil && host != nil {
+		logger = host
+	}
+
+	var server rpc.ResourceProviderServer = &xyzProvider{
+		host:     host,
+		logger:   logger,
+		name:     opts.Registry.Package(),
+		version:  opts.Metadata.Version,
+		registry: opts.Registry,
+		metadata: opts.Metadata,
+		config:   opts.Config,
+
+		outputValidation: opts.OutputValidation,
+	}
+	for i := len(opts.Middlewares) - 1; i >= 0; i-- {
+		server = opts.Middlewares[i](server)
+	}
+	return server, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// OutputValidation controls whether the outputs of Create, Read and Update are checked against the output schema of
+// the resource, to catch resource functions that drift from the schema.
+type OutputValidation int
+
+const (
+	// OutputValidationDefault leaves the choice to the caller: providers created with New do not check outputs,
+	// while providertest.New fails requests whose outputs do not match the schema.
+	OutputValidationDefault OutputValidation = iota
+	// OutputValidationOff does not check outputs.
+	OutputValidationOff
+	// OutputValidationWarn logs a warning for every output that does not match the schema.
+	OutputValidationWarn
+	// OutputValidationError fails the request if an output does not match the schema.
+	OutputValidationError
+)
+
+// validateOutputs checks that the outputs have all required properties of the resource schema, and only properties
+// of the schema with values of the declared types. It returns a message for each mismatch.
+func validateOutputs(res *resources.CustomResource, outputs resource.PropertyMap) []string {
+	v := &outputValidator{res: res}
+	v.validateObject("", res.Schema.Properties, res.Schema.Required, outputs)
+	return v.problems
+}
+
+type outputValidator struct {
+	res      *resources.CustomResource
+	problems []string
+}
+
+func (v *outputValidator) fail(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *outputValidator) validateObject(prefix string, props map[string]schema.PropertySpec, required []string,
+	values resource.PropertyMap) {
+
+	for _, name := range required {
+		if value, ok := values[resource.PropertyKey(name)]; !ok || value.IsNull() {
+			v.fail("required output %q is missing", prefix+name)
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		if prefix == "" && name == resources.StateVersionKey {
+			continue
+		}
+		prop, ok := props[name]
+		if !ok {
+			v.fail("output %q is not a property of the schema", prefix+name)
+			continue
+		}
+		v.validateValue(prefix+name, prop.TypeSpec, values[resource.PropertyKey(name)])
+	}
+}
+
+func (v *outputValidator) validateValue(path string, spec schema.TypeSpec, value resource.PropertyValue) {
+	for value.IsSecret() {
+		value = value.SecretValue().Element
+	}
+	if value.IsNull() || value.IsComputed() || value.IsOutput() {
+		return
+	}
+	if spec.Ref != "" {
+		v.validateRef(path, spec.Ref, value)
+		return
+	}
+
+	var ok bool
+	switch spec.Type {
+	case "string":
+		ok = value.IsString()
+	case "number":
+		ok = value.IsNumber()
+	case "integer":
+		ok = value.IsNumber() && value.NumberValue() == math.Trunc(value.NumberValue())
+	case "boolean":
+		ok = value.IsBool()
+	case "array":
+		if ok = value.IsArray(); ok && spec.Items != nil {
+			for i, item := range value.ArrayValue() {
+				v.validateValue(fmt.Sprintf("%s[%d]", path, i), *spec.Items, item)
+			}
+		}
+	case "object":
+		if ok = value.IsObject(); ok && spec.AdditionalProperties != nil {
+			obj := value.ObjectValue()
+			for _, k := range obj.StableKeys() {
+				v.validateValue(fmt.Sprintf("%s[%q]", path, k), *spec.AdditionalProperties, obj[k])
+			}
+		}
+	default:
+		return
+	}
+	if !ok {
+		v.fail("output %q should be of type %s, got %v", path, spec.Type, value.TypeString())
+	}
+}
+
+// validateRef validates a value of an auxiliary type of the resource by its underlying type. Objects are validated
+// property by property. References to other packages are not followed.
+func (v *outputValidator) validateRef(path, ref string, value resource.PropertyValue) {
+	const prefix = "#/types/"
+	if !strings.HasPrefix(ref, prefix) {
+		return
+	}
+	typ, ok := v.res.Types[strings.TrimPrefix(ref, prefix)]
+	if !ok {
+		return
+	}
+	if typ.Type == "object" && value.IsObject() {
+		v.validateObject(path+".", typ.Properties, typ.Required, value.ObjectValue())
+		return
+	}
+	v.validateValue(path, schema.TypeSpec{Type: typ.Type}, value)
+}
+
+// checkOutputs validates the outputs of a resource according to the output validation of the provider.
+func (p *xyzProvider) checkOutputs(ctx context.Context, urn resource.URN, res *resources.CustomResource,
+	outputs resource.PropertyMap) error {
+
+	if p.outputValidation == OutputValidationDefault || p.outputValidation == OutputValidationOff {
+		return nil
+	}
+	problems := validateOutputs(res, outputs)
+	if len(problems) == 0 {
+		return nil
+	}
+	if p.outputValidation == OutputValidationError {
+		return fmt.Errorf("outputs of %s do not match the schema: %s", urn.Type(), strings.Join(problems, "; "))
+	}
+
+	w := &warnings{}
+	for _, msg := range problems {
+		w.add("outputs of %s do not match the schema: %s", urn.Type(), msg)
+	}
+	return p.warn(ctx, urn, w)
+}
@@ -0,0 +1,132 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+func TestValidateOutputs(t *testing.T) {
+	res := &resources.CustomResource{
+		Schema: &schema.ResourceSpec{
+			ObjectTypeSpec: schema.ObjectTypeSpec{
+				Properties: map[string]schema.PropertySpec{
+					"name":  {TypeSpec: schema.TypeSpec{Type: "string"}},
+					"count": {TypeSpec: schema.TypeSpec{Type: "integer"}},
+					"tags": {TypeSpec: schema.TypeSpec{
+						Type: "array", Items: &schema.TypeSpec{Ref: "#/types/xyz:index:Tag"},
+					}},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Types: map[string]schema.ComplexTypeSpec{
+			"xyz:index:Tag": {ObjectTypeSpec: schema.ObjectTypeSpec{
+				Type:       "object",
+				Properties: map[string]schema.PropertySpec{"key": {TypeSpec: schema.TypeSpec{Type: "string"}}},
+				Required:   []string{"key"},
+			}},
+		},
+	}
+
+	valid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":                    &resource.Secret{Element: resource.NewStringProperty("a")},
+		"count":                   2,
+		"tags":                    []interface{}{map[string]interface{}{"key": "k"}},
+		resources.StateVersionKey: 1,
+	})
+	if problems := validateOutputs(res, valid); len(problems) != 0 {
+		t.Errorf("expected valid outputs, got %v", problems)
+	}
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"count": 2.5,
+		"tags":  []interface{}{map[string]interface{}{"value": "v"}},
+		"extra": true,
+	})
+	expected := []string{
+		`required output "name" is missing`,
+		`output "count" should be of type integer, got number`,
+		`output "extra" is not a property of the schema`,
+		`required output "tags[0].key" is missing`,
+		`output "tags[0].value" is not a property of the schema`,
+	}
+	problems := validateOutputs(res, invalid)
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected problems\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(problems, "\n"))
+	}
+}
+
+type logRecorder []string
+
+func (l *logRecorder) Log(_ context.Context, sev diag.Severity, _ resource.URN, msg string) error {
+	*l = append(*l, string(sev)+": "+msg)
+	return nil
+}
+
+// mismatchedOutputs returns a registry with a Widget whose outputs do not match its schema.
+func mismatchedOutputs() *resources.Registry {
+	registry := resources.NewRegistry("xyz")
+	registry.MustRegister("xyz:index:Widget", &resources.CustomResource{
+		Inputs:  struct{}{},
+		Outputs: struct{}{},
+		Create: func(_ context.Context, _ map[string]interface{}) (string, map[string]interface{}, error) {
+			return "widget", map[string]interface{}{"color": "red"}, nil
+		},
+	})
+	return registry
+}
+
+func TestOutputValidationIsOffByDefault(t *testing.T) {
+	logs := &logRecorder{}
+	server, err := New(nil, Options{Registry: mismatchedOutputs(), Logger: logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urn := resource.NewURN("test", "outputs", "", "xyz:index:Widget", "w")
+	if _, err := server.Create(context.Background(), &rpc.CreateRequest{Urn: string(urn)}); err != nil {
+		t.Fatalf("expected outputs not to be validated, got %v", err)
+	}
+	if len(*logs) != 0 {
+		t.Errorf("expected no warnings, got %v", *logs)
+	}
+}
+
+func TestOutputValidationWarns(t *testing.T) {
+	logs := &logRecorder{}
+	server, err := New(nil, Options{Registry: mismatchedOutputs(), Logger: logs, OutputValidation: OutputValidationWarn})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urn := resource.NewURN("test", "outputs", "", "xyz:index:Widget", "w")
+	if _, err := server.Create(context.Background(), &rpc.CreateRequest{Urn: string(urn)}); err != nil {
+		t.Fatalf("expected mismatched outputs to only warn, got %v", err)
+	}
+	expected := `warning: outputs of xyz:index:Widget do not match the schema: output "color" is not a property ` +
+		`of the schema`
+	if len(*logs) != 1 || (*logs)[0] != expected {
+		t.Errorf("expected the warning %q, got %v", expected, *logs)
+	}
+}
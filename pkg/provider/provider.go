@@ -16,36 +16,104 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
 	"github.com/pulumi/pulumi/pkg/v3/resource/provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"strings"
 
 	pbempty "github.com/golang/protobuf/ptypes/empty"
 )
 
 type xyzProvider struct {
-	host    *provider.HostClient
-	name    string
-	version string
+	host     *provider.HostClient
+	logger   Logger
+	name     string
+	version  string
+	registry *resources.Registry
+	metadata resources.Metadata
+	config   schema.ConfigSpec
+
+	outputValidation OutputValidation
+
+	// Configuration values passed to resource functions, set by Configure.
+	configValues map[string]interface{}
 }
 
-func makeProvider(host *provider.HostClient, name, version string) (rpc.ResourceProviderServer, error) {
-	// Return the new provider
-	return &xyzProvider{
-		host:    host,
-		name:    name,
-		version: version,
-	}, nil
+// warn sends warnings about a resource to the logger. Warnings are dropped if there is no logger.
+func (p *xyzProvider) warn(ctx context.Context, urn resource.URN, w *warnings) error {
+	if p.logger == nil {
+		return nil
+	}
+	for _, msg := range w.messages {
+		if err := p.logger.Log(ctx, diag.Warning, urn, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withConfig returns a context that carries the provider configuration to resource functions.
+func (p *xyzProvider) withConfig(ctx context.Context) context.Context {
+	return resources.WithConfig(ctx, p.configValues)
+}
+
+// configResource describes the provider configuration as a resource, so that it is checked like resource inputs.
+func (p *xyzProvider) configResource() *resources.CustomResource {
+	return &resources.CustomResource{
+		Schema: &schema.ResourceSpec{
+			InputProperties: p.config.Variables,
+			RequiredInputs:  p.config.Required,
+		},
+	}
+}
+
+// getResource returns the resource implementing the given type, resolving the old tokens of renamed types.
+func (p *xyzProvider) getResource(typ tokens.Type) (*resources.CustomResource, error) {
+	res, _, ok := p.registry.Lookup(typ.String())
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type %q", typ)
+	}
+	return res, nil
 }
 
 // CheckConfig validates the configuration for this provider.
 func (p *xyzProvider) CheckConfig(ctx context.Context, req *rpc.CheckRequest) (*rpc.CheckResponse, error) {
-	return &rpc.CheckResponse{Inputs: req.GetNews()}, nil
+	news, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
+		KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Engines send configuration values that were set on the command line as strings.
+	for name, prop := range p.config.Variables {
+		key := resource.PropertyKey(name)
+		if value, ok := news[key]; ok && value.IsString() {
+			if parsed, err := parseDefault(prop.Type, value.StringValue()); err == nil {
+				news[key] = parsed
+			}
+		}
+	}
+
+	v := &inputValidator{res: p.configResource()}
+	v.failures = applyDefaults(v.res, news)
+	v.validateRequired(news)
+	v.validateInputs(news)
+
+	inputs, err := plugin.MarshalProperties(news, plugin.MarshalOptions{KeepUnknowns: true, KeepSecrets: true})
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.CheckResponse{Inputs: inputs, Failures: v.failures}, nil
 }
 
 // DiffConfig diffs the configuration for this provider.
@@ -55,6 +123,26 @@ func (p *xyzProvider) DiffConfig(ctx context.Context, req *rpc.DiffRequest) (*rp
 
 // Configure configures the resource provider with "globals" that control its behavior.
 func (p *xyzProvider) Configure(_ context.Context, req *rpc.ConfigureRequest) (*rpc.ConfigureResponse, error) {
+	if args := req.GetArgs(); args != nil {
+		config, err := plugin.UnmarshalProperties(args, plugin.MarshalOptions{SkipNulls: true})
+		if err != nil {
+			return nil, err
+		}
+		p.configValues = config.Mappable()
+		return &rpc.ConfigureResponse{}, nil
+	}
+
+	// Older engines only send variables as strings keyed by `pkg:config:name`.
+	config := resource.PropertyMap{}
+	for key, str := range req.GetVariables() {
+		name := key[strings.LastIndex(key, ":")+1:]
+		value, err := parseDefault(p.config.Variables[name].Type, str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of configuration variable %s", str, key)
+		}
+		config[resource.PropertyKey(name)] = value
+	}
+	p.configValues = config.Mappable()
 	return &rpc.ConfigureResponse{}, nil
 }
 
@@ -78,30 +166,115 @@ func (p *xyzProvider) StreamInvoke(req *rpc.InvokeRequest, server rpc.ResourcePr
 func (p *xyzProvider) Check(ctx context.Context, req *rpc.CheckRequest) (*rpc.CheckResponse, error) {
 	typ := resource.URN(req.GetUrn()).Type()
 
-	res, ok := resources.Resources[typ.String()]
+	res, tok, ok := p.registry.Lookup(typ.String())
 	if !ok {
 		return nil, fmt.Errorf("unknown resource type %q", typ)
 	}
-	if res.Create == nil {
-		return nil, fmt.Errorf("resource type %q has no Create operation defined", typ)
+	w := &warnings{}
+	if tok != typ.String() {
+		w.add("resource type %q has been renamed to %q, please update your program", typ, tok)
 	}
-	return &rpc.CheckResponse{Inputs: req.News, Failures: nil}, nil
+
+	news, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
+		KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{
+		KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.deprecations(tok, res, news)
+	if err := p.warn(ctx, resource.URN(req.GetUrn()), w); err != nil {
+		return nil, err
+	}
+
+	v := &inputValidator{res: res}
+	v.failures = applyDefaults(res, news)
+	if err := applyAutoName(res, resource.URN(req.GetUrn()), req.GetRandomSeed(), olds, news); err != nil {
+		return nil, err
+	}
+	v.validateRequired(news)
+	v.validateInputs(news)
+
+	if res.Check != nil {
+		checked, failures, err := res.Check(ctx, olds.Mappable(), news.Mappable())
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range failures {
+			v.fail(f.Property, "%s", f.Reason)
+		}
+		if checked != nil {
+			news = resource.NewPropertyMapFromMap(checked)
+		}
+	}
+
+	inputs, err := plugin.MarshalProperties(news, plugin.MarshalOptions{KeepUnknowns: true, KeepSecrets: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.CheckResponse{Inputs: inputs, Failures: v.failures}, nil
 }
 
 // Diff checks what impacts a hypothetical update will have on the resource's properties.
 func (p *xyzProvider) Diff(ctx context.Context, req *rpc.DiffRequest) (*rpc.DiffResponse, error) {
 	typ := resource.URN(req.GetUrn()).Type()
-	res := resources.Resources[typ.String()]
-	if res.Update == nil {
-		var replaces []string
-		for prop := range res.Schema.InputProperties {
-			replaces = append(replaces, prop)
+	res, err := p.getResource(typ)
+	if err != nil {
+		return nil, err
+	}
+	ctx = p.withConfig(ctx)
+
+	olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{
+		KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if olds, err = upgradeState(ctx, res, olds); err != nil {
+		return nil, err
+	}
+	news, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
+		KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	if res.Diff != nil {
+		if changed, err = res.Diff(ctx, olds.Mappable(), news.Mappable()); err != nil {
+			return nil, err
 		}
-		return &rpc.DiffResponse{
-			Replaces: replaces,
-		}, nil
+	} else {
+		changed = changedInputs(res, olds, news)
 	}
-	return &rpc.DiffResponse{}, nil
+	changed = withoutIgnored(changed, req.GetIgnoreChanges())
+	if len(changed) == 0 {
+		return &rpc.DiffResponse{Changes: rpc.DiffResponse_DIFF_NONE}, nil
+	}
+
+	var replaces []string
+	for _, name := range changed {
+		if res.ForcesReplacement(name) {
+			replaces = append(replaces, name)
+		}
+	}
+	return &rpc.DiffResponse{
+		Changes:             rpc.DiffResponse_DIFF_SOME,
+		Diffs:               changed,
+		Replaces:            replaces,
+		DeleteBeforeReplace: len(replaces) > 0 && res.DeleteBeforeReplace,
+		DetailedDiff:        detailedDiff(changed, replaces, olds, news),
+		HasDetailedDiff:     true,
+	}, nil
 }
 
 // Create allocates a new instance of the provided resource and returns its unique ID afterwards.
@@ -114,16 +287,22 @@ func (p *xyzProvider) Create(ctx context.Context, req *rpc.CreateRequest) (*rpc.
 	}
 	inputsMap := inputs.Mappable()
 
-	res := resources.Resources[typ.String()]
+	res, err := p.getResource(typ)
+	if err != nil {
+		return nil, err
+	}
+	ctx = p.withConfig(ctx)
 	id, outputsMap, err := res.Create(ctx, inputsMap)
 	if err != nil {
 		return nil, err
 	}
 
-	outputs, err := plugin.MarshalProperties(
-		resource.NewPropertyMapFromMap(outputsMap),
-		plugin.MarshalOptions{SkipNulls: true},
-	)
+	outputProps := resource.NewPropertyMapFromMap(outputsMap)
+	if err := p.checkOutputs(ctx, resource.URN(req.GetUrn()), res, outputProps); err != nil {
+		return nil, err
+	}
+
+	outputs, err := plugin.MarshalProperties(versionState(res, outputProps), plugin.MarshalOptions{SkipNulls: true})
 	if err != nil {
 		return nil, err
 	}
@@ -146,9 +325,25 @@ func (p *xyzProvider) Read(ctx context.Context, req *rpc.ReadRequest) (*rpc.Read
 		return nil, err
 	}
 
-	res := resources.Resources[typ.String()]
+	res, err := p.getResource(typ)
+	if err != nil {
+		return nil, err
+	}
+	ctx = p.withConfig(ctx)
+	if oldState, err = upgradeState(ctx, res, oldState); err != nil {
+		return nil, err
+	}
+	stateOpts := plugin.MarshalOptions{KeepSecrets: true, KeepUnknowns: true, SkipNulls: true}
 	if res.Read == nil {
-		return &rpc.ReadResponse{Id: id, Properties: req.GetProperties(), Inputs: req.GetInputs()}, nil
+		// Without a Read operation the state can only be refreshed from itself, so there is nothing to import from.
+		if req.GetProperties() == nil {
+			return nil, fmt.Errorf("resource type %q does not support import", typ)
+		}
+		outputs, err := plugin.MarshalProperties(versionState(res, oldState.Copy()), stateOpts)
+		if err != nil {
+			return nil, err
+		}
+		return &rpc.ReadResponse{Id: id, Properties: outputs, Inputs: req.GetInputs()}, nil
 	}
 
 	outputsMap, exists, err := res.Read(ctx, oldState.Mappable())
@@ -159,15 +354,20 @@ func (p *xyzProvider) Read(ctx context.Context, req *rpc.ReadRequest) (*rpc.Read
 		return &rpc.ReadResponse{Id: ""}, nil
 	}
 
-	outputs, err := plugin.MarshalProperties(
-		resource.NewPropertyMapFromMap(outputsMap),
-		plugin.MarshalOptions{KeepSecrets: true, KeepUnknowns: true, SkipNulls: true},
-	)
+	outputProps := resource.NewPropertyMapFromMap(outputsMap)
+	if err := p.checkOutputs(ctx, resource.URN(req.GetUrn()), res, outputProps); err != nil {
+		return nil, err
+	}
+	outputs, err := plugin.MarshalProperties(versionState(res, outputProps), stateOpts)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := plugin.MarshalProperties(oldState, stateOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &rpc.ReadResponse{Id: id, Properties: outputs, Inputs: req.GetProperties()}, nil
+	return &rpc.ReadResponse{Id: id, Properties: outputs, Inputs: inputs}, nil
 }
 
 // Update updates an existing resource with new values.
@@ -180,19 +380,33 @@ func (p *xyzProvider) Update(ctx context.Context, req *rpc.UpdateRequest) (*rpc.
 	}
 	inputsMap := inputs.Mappable()
 
-	res := resources.Resources[typ.String()]
+	res, err := p.getResource(typ)
+	if err != nil {
+		return nil, err
+	}
+	ctx = p.withConfig(ctx)
 	if res.Update == nil {
 		return nil, fmt.Errorf("resource type %q has no Update operation defined", typ)
 	}
+	// Update does not see the old state, but must not overwrite state written by a newer provider.
+	olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{SkipNulls: true})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stateVersion(res, olds); err != nil {
+		return nil, err
+	}
 	outputsMap, err := res.Update(ctx, inputsMap)
 	if err != nil {
 		return nil, err
 	}
 
-	outputs, err := plugin.MarshalProperties(
-		resource.NewPropertyMapFromMap(outputsMap),
-		plugin.MarshalOptions{SkipNulls: true},
-	)
+	outputProps := resource.NewPropertyMapFromMap(outputsMap)
+	if err := p.checkOutputs(ctx, resource.URN(req.GetUrn()), res, outputProps); err != nil {
+		return nil, err
+	}
+
+	outputs, err := plugin.MarshalProperties(versionState(res, outputProps), plugin.MarshalOptions{SkipNulls: true})
 	if err != nil {
 		return nil, err
 	}
@@ -206,12 +420,19 @@ func (p *xyzProvider) Update(ctx context.Context, req *rpc.UpdateRequest) (*rpc.
 // to still exist.
 func (p *xyzProvider) Delete(ctx context.Context, req *rpc.DeleteRequest) (*pbempty.Empty, error) {
 	typ := resource.URN(req.GetUrn()).Type()
-	res := resources.Resources[typ.String()]
+	res, err := p.getResource(typ)
+	if err != nil {
+		return nil, err
+	}
+	ctx = p.withConfig(ctx)
 	if res.Delete != nil {
 		inputs, err := plugin.UnmarshalProperties(req.GetProperties(), plugin.MarshalOptions{SkipNulls: true})
 		if err != nil {
 			return nil, err
 		}
+		if inputs, err = upgradeState(ctx, res, inputs); err != nil {
+			return nil, err
+		}
 		inputsMap := inputs.Mappable()
 
 		err = res.Delete(ctx, inputsMap)
@@ -228,6 +449,16 @@ func (p *xyzProvider) Construct(_ context.Context, _ *rpc.ConstructRequest) (*rp
 	return nil, status.Error(codes.Unimplemented, "Construct is not yet implemented")
 }
 
+// Call dynamically executes a method in the provider associated with a component resource.
+func (p *xyzProvider) Call(_ context.Context, _ *rpc.CallRequest) (*rpc.CallResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "Call is not yet implemented")
+}
+
+// Attach attaches to the engine for an already running provider.
+func (p *xyzProvider) Attach(_ context.Context, _ *rpc.PluginAttach) (*pbempty.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "Attach is not yet implemented")
+}
+
 // GetPluginInfo returns generic information about this plugin, like its version.
 func (p *xyzProvider) GetPluginInfo(context.Context, *pbempty.Empty) (*rpc.PluginInfo, error) {
 	return &rpc.PluginInfo{
@@ -237,7 +468,18 @@ func (p *xyzProvider) GetPluginInfo(context.Context, *pbempty.Empty) (*rpc.Plugi
 
 // GetSchema returns the JSON-serialized schema for the provider.
 func (p *xyzProvider) GetSchema(ctx context.Context, req *rpc.GetSchemaRequest) (*rpc.GetSchemaResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "GetSchema is not yet implemented")
+	if v := req.GetVersion(); v != 0 {
+		return nil, fmt.Errorf("unsupported schema version %d", v)
+	}
+	spec, err := p.registry.PackageSpec(p.metadata, p.config)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.GetSchemaResponse{Schema: string(bytes)}, nil
 }
 
 // Cancel signals the provider to gracefully shut down and abort any ongoing resource operations.
@@ -0,0 +1,390 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// Redacted replaces secret values in recorded interactions.
+const Redacted = "[secret]"
+
+// Interaction is a request to the provider together with its response or error, as written by Recorder.
+type Interaction struct {
+	// Name of the gRPC method, e.g. Check.
+	Method string `json:"method"`
+	// Request message in the JSON encoding of protocol buffers.
+	Request json.RawMessage `json:"request"`
+	// Response message in the JSON encoding of protocol buffers, unless the request failed.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Error returned by the provider, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Redactor encodes requests and responses as interactions with secret values replaced by Redacted. Values are
+// secret if they are marked as secrets on the wire, or if they belong to a property declared secret in the schema of
+// the resource or of the provider configuration, including properties of nested object types. Secret strings are
+// also removed from the reasons of check failures and from errors.
+type Redactor struct {
+	registry *resources.Registry
+	config   schema.ConfigSpec
+}
+
+// NewRedactor creates a redactor for the resources of the registry and the given configuration schema.
+func NewRedactor(registry *resources.Registry, config schema.ConfigSpec) *Redactor {
+	return &Redactor{registry: registry, config: config}
+}
+
+// Interaction encodes a request and its response or error.
+func (r *Redactor) Interaction(method string, req, resp proto.Message, err error) (Interaction, error) {
+	red := r.redaction(req)
+	interaction := Interaction{Method: method}
+
+	reqFields, encodeErr := red.redactMessage(req)
+	if encodeErr != nil {
+		return Interaction{}, encodeErr
+	}
+	var respFields map[string]interface{}
+	if err == nil {
+		if respFields, encodeErr = red.redactMessage(resp); encodeErr != nil {
+			return Interaction{}, encodeErr
+		}
+	}
+
+	// Secrets are collected from both messages before scrubbing free text, so that check failures are scrubbed of
+	// secret inputs too.
+	if interaction.Request, encodeErr = json.Marshal(reqFields); encodeErr != nil {
+		return Interaction{}, encodeErr
+	}
+	if err != nil {
+		interaction.Error = red.scrubString(err.Error())
+		return interaction, nil
+	}
+	red.scrubFailures(respFields)
+	if interaction.Response, encodeErr = json.Marshal(respFields); encodeErr != nil {
+		return Interaction{}, encodeErr
+	}
+	return interaction, nil
+}
+
+// redaction returns the state for redacting a request and its response, with the schema of the resource or
+// configuration the request is about.
+func (r *Redactor) redaction(req proto.Message) *redaction {
+	red := &redaction{types: map[string]schema.ComplexTypeSpec{}, values: map[string]bool{}}
+	for _, tok := range r.registry.Tokens() {
+		res, _, _ := r.registry.Lookup(tok)
+		for typeTok, typ := range res.Types {
+			red.types[typeTok] = typ
+		}
+	}
+
+	var typ string
+	if withURN, ok := req.(interface{ GetUrn() string }); ok {
+		typ = string(resource.URN(withURN.GetUrn()).Type())
+	}
+	if res, _, ok := r.registry.Lookup(typ); ok && res.Schema != nil {
+		red.props = map[string]schema.PropertySpec{}
+		for name, prop := range res.Schema.Properties {
+			red.props[name] = prop
+		}
+		for name, prop := range res.Schema.InputProperties {
+			prop.Secret = prop.Secret || red.props[name].Secret
+			red.props[name] = prop
+		}
+	} else {
+		red.props = r.config.Variables
+	}
+	return red
+}
+
+// redaction redacts the messages of one interaction and collects the secret strings it finds.
+type redaction struct {
+	props  map[string]schema.PropertySpec
+	types  map[string]schema.ComplexTypeSpec
+	values map[string]bool
+}
+
+// redactMessage decodes a message from JSON and redacts the secret values of its property maps.
+func (red *redaction) redactMessage(msg proto.Message) (map[string]interface{}, error) {
+	str, err := (&jsonpb.Marshaler{OrigName: true}).MarshalToString(msg)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(str), &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range fields {
+		switch name {
+		case "olds", "news", "properties", "inputs", "args":
+			if props, ok := value.(map[string]interface{}); ok {
+				red.redactObject(props, red.props)
+			}
+		case "variables":
+			// Configuration variables are keyed by `pkg:config:name`.
+			if vars, ok := value.(map[string]interface{}); ok {
+				for key, v := range vars {
+					if prop, ok := red.props[key[strings.LastIndex(key, ":")+1:]]; ok {
+						vars[key] = red.redactValue(v, prop.TypeSpec, prop.Secret)
+					}
+				}
+			}
+		}
+		fields[name] = red.redactSecrets(value)
+	}
+	return fields, nil
+}
+
+// redactObject redacts the properties of an object that are declared secret, recursing into nested types.
+func (red *redaction) redactObject(values map[string]interface{}, props map[string]schema.PropertySpec) {
+	for key, v := range values {
+		if prop, ok := props[key]; ok {
+			values[key] = red.redactValue(v, prop.TypeSpec, prop.Secret)
+		}
+	}
+}
+
+func (red *redaction) redactValue(v interface{}, typ schema.TypeSpec, secret bool) interface{} {
+	if secret {
+		red.collect(v)
+		return Redacted
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if typ.AdditionalProperties != nil {
+			for key, elem := range v {
+				v[key] = red.redactValue(elem, *typ.AdditionalProperties, false)
+			}
+		} else if t, ok := red.types[strings.TrimPrefix(typ.Ref, "#/types/")]; ok && typ.Ref != "" {
+			red.redactObject(v, t.Properties)
+		}
+	case []interface{}:
+		if typ.Items != nil {
+			for i, elem := range v {
+				v[i] = red.redactValue(elem, *typ.Items, false)
+			}
+		}
+	}
+	return v
+}
+
+// redactSecrets replaces all values marked as secrets on the wire.
+func (red *redaction) redactSecrets(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v[resource.SigKey] == resource.SecretSig {
+			red.collect(v["value"])
+			return map[string]interface{}{resource.SigKey: resource.SecretSig, "value": Redacted}
+		}
+		for k, elem := range v {
+			v[k] = red.redactSecrets(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = red.redactSecrets(elem)
+		}
+	}
+	return v
+}
+
+// collect records the strings of a secret value.
+func (red *redaction) collect(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		if v != "" && v != Redacted {
+			red.values[v] = true
+		}
+	case map[string]interface{}:
+		for _, elem := range v {
+			red.collect(elem)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			red.collect(elem)
+		}
+	}
+}
+
+// scrubFailures replaces the secret strings in the reasons of the check failures of a decoded response. Other
+// strings, such as URNs and IDs, are left alone, because short secrets would corrupt them.
+func (red *redaction) scrubFailures(fields map[string]interface{}) {
+	failures, _ := fields["failures"].([]interface{})
+	for _, f := range failures {
+		if failure, ok := f.(map[string]interface{}); ok {
+			if reason, ok := failure["reason"].(string); ok {
+				failure["reason"] = red.scrubString(reason)
+			}
+		}
+	}
+}
+
+// scrubString replaces the secret strings in a string, longest first.
+func (red *redaction) scrubString(s string) string {
+	values := make([]string, 0, len(red.values))
+	for value := range red.values {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, Redacted)
+	}
+	return s
+}
+
+// Recorder returns a middleware that writes every request and its response to w as a JSON-encoded Interaction per
+// line. Recordings can be fed back into a provider with providertest.Replay.
+func Recorder(w io.Writer, redactor *Redactor) Middleware {
+	return func(next rpc.ResourceProviderServer) rpc.ResourceProviderServer {
+		return &recorder{next: next, redactor: redactor, enc: json.NewEncoder(w)}
+	}
+}
+
+type recorder struct {
+	next     rpc.ResourceProviderServer
+	redactor *Redactor
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// record writes an interaction. Failing to record must not fail the request, so errors are dropped.
+func (r *recorder) record(method string, req, resp proto.Message, err error) {
+	interaction, encodeErr := r.redactor.Interaction(method, req, resp, err)
+	if encodeErr != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(interaction)
+}
+
+func (r *recorder) GetSchema(ctx context.Context, req *rpc.GetSchemaRequest) (*rpc.GetSchemaResponse, error) {
+	resp, err := r.next.GetSchema(ctx, req)
+	r.record("GetSchema", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) CheckConfig(ctx context.Context, req *rpc.CheckRequest) (*rpc.CheckResponse, error) {
+	resp, err := r.next.CheckConfig(ctx, req)
+	r.record("CheckConfig", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) DiffConfig(ctx context.Context, req *rpc.DiffRequest) (*rpc.DiffResponse, error) {
+	resp, err := r.next.DiffConfig(ctx, req)
+	r.record("DiffConfig", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Configure(ctx context.Context, req *rpc.ConfigureRequest) (*rpc.ConfigureResponse, error) {
+	resp, err := r.next.Configure(ctx, req)
+	r.record("Configure", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Invoke(ctx context.Context, req *rpc.InvokeRequest) (*rpc.InvokeResponse, error) {
+	resp, err := r.next.Invoke(ctx, req)
+	r.record("Invoke", req, resp, err)
+	return resp, err
+}
+
+// StreamInvoke is passed through without being recorded.
+func (r *recorder) StreamInvoke(req *rpc.InvokeRequest, server rpc.ResourceProvider_StreamInvokeServer) error {
+	return r.next.StreamInvoke(req, server)
+}
+
+func (r *recorder) Check(ctx context.Context, req *rpc.CheckRequest) (*rpc.CheckResponse, error) {
+	resp, err := r.next.Check(ctx, req)
+	r.record("Check", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Diff(ctx context.Context, req *rpc.DiffRequest) (*rpc.DiffResponse, error) {
+	resp, err := r.next.Diff(ctx, req)
+	r.record("Diff", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Create(ctx context.Context, req *rpc.CreateRequest) (*rpc.CreateResponse, error) {
+	resp, err := r.next.Create(ctx, req)
+	r.record("Create", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Read(ctx context.Context, req *rpc.ReadRequest) (*rpc.ReadResponse, error) {
+	resp, err := r.next.Read(ctx, req)
+	r.record("Read", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Update(ctx context.Context, req *rpc.UpdateRequest) (*rpc.UpdateResponse, error) {
+	resp, err := r.next.Update(ctx, req)
+	r.record("Update", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Delete(ctx context.Context, req *rpc.DeleteRequest) (*pbempty.Empty, error) {
+	resp, err := r.next.Delete(ctx, req)
+	r.record("Delete", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Construct(ctx context.Context, req *rpc.ConstructRequest) (*rpc.ConstructResponse, error) {
+	resp, err := r.next.Construct(ctx, req)
+	r.record("Construct", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Cancel(ctx context.Context, req *pbempty.Empty) (*pbempty.Empty, error) {
+	resp, err := r.next.Cancel(ctx, req)
+	r.record("Cancel", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) GetPluginInfo(ctx context.Context, req *pbempty.Empty) (*rpc.PluginInfo, error) {
+	resp, err := r.next.GetPluginInfo(ctx, req)
+	r.record("GetPluginInfo", req, resp, err)
+	return resp, err
+}
+
+func (r *recorder) Call(ctx context.Context, req *rpc.CallRequest) (*rpc.CallResponse, error) {
+	resp, err := r.next.Call(ctx, req)
+	r.record("Call", req, resp, err)
+	return resp, err
+}
+
+// Attach is passed through without being recorded.
+func (r *recorder) Attach(ctx context.Context, req *rpc.PluginAttach) (*pbempty.Empty, error) {
+	return r.next.Attach(ctx, req)
+}
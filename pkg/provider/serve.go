@@ -20,11 +20,15 @@ import (
 	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
 )
 
-// Serve launches the gRPC server for the resource provider.
-func Serve(providerName, version string) {
+// Serve launches the gRPC server for a resource provider created with the given options.
+func Serve(opts Options) {
+	if opts.Registry == nil {
+		cmdutil.ExitError("a resource registry is required")
+	}
+
 	// Start gRPC service.
-	err := provider.Main(providerName, func(host *provider.HostClient) (rpc.ResourceProviderServer, error) {
-		return makeProvider(host, providerName, version)
+	err := provider.Main(opts.Registry.Package(), func(host *provider.HostClient) (rpc.ResourceProviderServer, error) {
+		return New(host, opts)
 	})
 	if err != nil {
 		cmdutil.ExitError(err.Error())
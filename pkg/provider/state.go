@@ -0,0 +1,78 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+const stateVersionKey = resource.PropertyKey(resources.StateVersionKey)
+
+// upgradeState removes the state version from stored outputs and runs the upgraders of the resource, one version at a
+// time, until the state has the current shape. State without a version has version 0.
+func upgradeState(ctx context.Context, res *resources.CustomResource, state resource.PropertyMap) (
+	resource.PropertyMap, error) {
+
+	version, err := stateVersion(res, state)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := state[stateVersionKey]; ok {
+		state = state.Copy()
+		delete(state, stateVersionKey)
+	}
+
+	for ; version < res.StateVersion; version++ {
+		upgrade, ok := res.StateUpgraders[version]
+		if !ok {
+			continue
+		}
+		upgraded, err := upgrade(ctx, state.Mappable())
+		if err != nil {
+			return nil, fmt.Errorf("upgrading state from version %d: %w", version, err)
+		}
+		state = resource.NewPropertyMapFromMap(upgraded)
+	}
+	return state, nil
+}
+
+// stateVersion returns the version of stored outputs, and fails if it is newer than the provider supports.
+func stateVersion(res *resources.CustomResource, state resource.PropertyMap) (int, error) {
+	v, ok := state[stateVersionKey]
+	if !ok {
+		return 0, nil
+	}
+	if !v.IsNumber() {
+		return 0, fmt.Errorf("invalid state version %v", v)
+	}
+	version := int(v.NumberValue())
+	if version > res.StateVersion {
+		return 0, fmt.Errorf("state has version %d, which is newer than version %d supported by this provider",
+			version, res.StateVersion)
+	}
+	return version, nil
+}
+
+// versionState records the current state version of the resource in its outputs.
+func versionState(res *resources.CustomResource, outputs resource.PropertyMap) resource.PropertyMap {
+	if res.StateVersion > 0 {
+		outputs[stateVersionKey] = resource.NewNumberProperty(float64(res.StateVersion))
+	}
+	return outputs
+}
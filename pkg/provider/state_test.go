@@ -0,0 +1,121 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+type volumeOutputs struct {
+	SizeGB int `pulumi:"sizeGb"`
+}
+
+// volume returns a resource at state version 3, which renamed `size` to `sizeMb` in version 0 and converted it to
+// `sizeGb` in version 2.
+func volume() *resources.CustomResource {
+	return &resources.CustomResource{
+		Inputs:       struct{}{},
+		Outputs:      volumeOutputs{},
+		StateVersion: 3,
+		StateUpgraders: map[int]func(context.Context, map[string]interface{}) (map[string]interface{}, error){
+			0: func(_ context.Context, state map[string]interface{}) (map[string]interface{}, error) {
+				return map[string]interface{}{"sizeMb": state["size"]}, nil
+			},
+			2: func(_ context.Context, state map[string]interface{}) (map[string]interface{}, error) {
+				return map[string]interface{}{"sizeGb": state["sizeMb"].(float64) / 1024}, nil
+			},
+		},
+		Create: func(context.Context, map[string]interface{}) (string, map[string]interface{}, error) {
+			return "vol", map[string]interface{}{"sizeGb": 1}, nil
+		},
+		Update: func(context.Context, map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"sizeGb": 2}, nil
+		},
+	}
+}
+
+func TestUpgradeState(t *testing.T) {
+	tests := []struct {
+		state    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{map[string]interface{}{"size": 2048}, map[string]interface{}{"sizeGb": 2}},
+		{map[string]interface{}{"sizeMb": 2048, "__stateVersion": 1}, map[string]interface{}{"sizeGb": 2}},
+		{map[string]interface{}{"sizeMb": 2048, "__stateVersion": 2}, map[string]interface{}{"sizeGb": 2}},
+		{map[string]interface{}{"sizeGb": 2, "__stateVersion": 3}, map[string]interface{}{"sizeGb": 2}},
+	}
+	for _, test := range tests {
+		upgraded, err := upgradeState(context.Background(), volume(), resource.NewPropertyMapFromMap(test.state))
+		if err != nil {
+			t.Errorf("upgrading %v: %v", test.state, err)
+			continue
+		}
+		if expected := resource.NewPropertyMapFromMap(test.expected); !upgraded.DeepEquals(expected) {
+			t.Errorf("expected %v to be upgraded to %v, got %v", test.state, expected, upgraded)
+		}
+	}
+
+	for _, state := range []map[string]interface{}{{"__stateVersion": 4}, {"__stateVersion": "3"}} {
+		if _, err := upgradeState(context.Background(), volume(), resource.NewPropertyMapFromMap(state)); err == nil {
+			t.Errorf("expected the state version of %v to be rejected", state)
+		}
+	}
+}
+
+func TestStateVersionIsStamped(t *testing.T) {
+	server := serve(t, volume())
+	ctx := context.Background()
+	outputs := func(props *structpb.Struct) resource.PropertyMap {
+		t.Helper()
+		m, err := plugin.UnmarshalProperties(props, plugin.MarshalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	created, err := server.Create(ctx, &rpc.CreateRequest{Urn: widgetURN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{"sizeGb": 1, "__stateVersion": 3})
+	if state := outputs(created.GetProperties()); !state.DeepEquals(expected) {
+		t.Errorf("expected the created state %v, got %v", expected, state)
+	}
+
+	updated, err := server.Update(ctx, &rpc.UpdateRequest{Urn: widgetURN, Olds: created.GetProperties()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = resource.NewPropertyMapFromMap(map[string]interface{}{"sizeGb": 2, "__stateVersion": 3})
+	if state := outputs(updated.GetProperties()); !state.DeepEquals(expected) {
+		t.Errorf("expected the updated state %v, got %v", expected, state)
+	}
+
+	// State written by a newer provider is not overwritten.
+	newer := marshal(t, resource.NewPropertyMapFromMap(map[string]interface{}{"sizeGb": 1, "__stateVersion": 4}))
+	_, err = server.Update(ctx, &rpc.UpdateRequest{Urn: widgetURN, Olds: newer})
+	if err == nil || !strings.Contains(err.Error(), "newer than version 3") {
+		t.Errorf("expected the newer state to be rejected, got %v", err)
+	}
+}
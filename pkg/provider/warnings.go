@@ -0,0 +1,52 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// warnings collects the distinct warnings raised while handling a single request.
+type warnings struct {
+	messages []string
+	seen     map[string]bool
+}
+
+func (w *warnings) add(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if w.seen == nil {
+		w.seen = map[string]bool{}
+	}
+	if !w.seen[msg] {
+		w.seen[msg] = true
+		w.messages = append(w.messages, msg)
+	}
+}
+
+// deprecations warns about the use of a deprecated resource and of deprecated input properties that are set.
+func (w *warnings) deprecations(token string, res *resources.CustomResource, inputs resource.PropertyMap) {
+	if msg := res.Schema.DeprecationMessage; msg != "" {
+		w.add("resource type %q is deprecated: %s", token, msg)
+	}
+	for _, name := range sortedKeys(res.Schema.InputProperties) {
+		msg := res.Schema.InputProperties[name].DeprecationMessage
+		if msg != "" && hasValue(inputs, resource.PropertyKey(name)) {
+			w.add("property %q of %q is deprecated: %s", name, token, msg)
+		}
+	}
+}
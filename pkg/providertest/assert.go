@@ -0,0 +1,92 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// AssertNoFailures fails the test if Check reported any failures.
+func AssertNoFailures(t testing.TB, result CheckResult) {
+	t.Helper()
+	for _, f := range result.Failures {
+		t.Errorf("unexpected check failure for %q: %s", f.Property, f.Reason)
+	}
+}
+
+// AssertFailure fails the test unless Check reported a failure for the property whose reason contains the given text.
+func AssertFailure(t testing.TB, result CheckResult, property, reason string) {
+	t.Helper()
+	for _, f := range result.Failures {
+		if f.Property == property && strings.Contains(f.Reason, reason) {
+			return
+		}
+	}
+	t.Errorf("expected a check failure for %q containing %q, got %v", property, reason, result.Failures)
+}
+
+// AssertDiff fails the test unless the detailed diff has exactly the given changes.
+func AssertDiff(t testing.TB, diff DiffResult, expected map[string]rpc.PropertyDiff_Kind) {
+	t.Helper()
+	if len(expected) == 0 && len(diff.DetailedDiff) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(diff.DetailedDiff, expected) {
+		t.Errorf("expected detailed diff %v, got %v", expected, diff.DetailedDiff)
+	}
+}
+
+// AssertOutputs fails the test unless the outputs contain the expected values. Properties not listed in expected are
+// ignored. Numbers may be given as any numeric Go type.
+func AssertOutputs(t testing.TB, outputs, expected map[string]interface{}) {
+	t.Helper()
+	normalized := resource.NewPropertyMapFromMap(expected).Mappable()
+	for _, k := range sortedKeys(normalized) {
+		actual, ok := outputs[k]
+		if !ok {
+			t.Errorf("expected output %q to be set", k)
+			continue
+		}
+		if !reflect.DeepEqual(actual, normalized[k]) {
+			t.Errorf("expected output %q to be %v, got %v", k, normalized[k], actual)
+		}
+	}
+}
+
+// AssertLogged fails the test unless a message containing the given text was logged with the given severity.
+func AssertLogged(t testing.TB, p *Provider, severity rpc.LogSeverity, message string) {
+	t.Helper()
+	for _, entry := range p.Logs() {
+		if entry.Severity == severity && strings.Contains(entry.Message, message) {
+			return
+		}
+	}
+	t.Errorf("expected a %v message containing %q, got %v", severity, message, p.Logs())
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	xyzprovider "github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+)
+
+// Conformance runs the same sanity checks against every resource of the registry, using the Samples declared on the
+// resource. For each resource, it creates the first sample and checks that
+//
+//   - the outputs of every operation conform to the Properties and Required outputs of the resource schema,
+//   - reading the resource right after a change returns the same outputs,
+//   - diffing the state against identical inputs reports no changes,
+//
+// then applies each following sample as an update, which must converge in the same way, and finally deletes the
+// resource. Resources without samples fail.
+func Conformance(t *testing.T, opts xyzprovider.Options) {
+	opts.OutputValidation = xyzprovider.OutputValidationError
+	for _, token := range opts.Registry.Tokens() {
+		token := token
+		res, _, _ := opts.Registry.Lookup(token)
+		t.Run(token, func(t *testing.T) {
+			if len(res.Samples) == 0 {
+				t.Fatalf("resource %q declares no Samples for the conformance tests", token)
+			}
+			conform(t, New(t, opts), token, res)
+		})
+	}
+}
+
+func conform(t *testing.T, p *Provider, token string, res *resources.CustomResource) {
+	var r *Resource
+	ok := t.Run("create", func(t *testing.T) {
+		var err error
+		if r, err = p.Up(token, "conformance", res.Samples[0]); err != nil {
+			t.Fatal(err)
+		}
+		assertConverged(t, p, r, res.Samples[0])
+	})
+	if !ok {
+		return
+	}
+
+	for i, sample := range res.Samples[1:] {
+		sample := sample
+		ok := t.Run(fmt.Sprintf("update %d", i+1), func(t *testing.T) {
+			if _, err := r.Update(sample); err != nil {
+				t.Fatal(err)
+			}
+			assertConverged(t, p, r, sample)
+		})
+		if !ok {
+			return
+		}
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		if err := r.Delete(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// assertConverged checks the state of a resource after a change: its outputs must be stable under Read and show no
+// changes against the inputs that produced them. Outputs that do not match the schema already failed the change.
+func assertConverged(t *testing.T, p *Provider, r *Resource, inputs map[string]interface{}) {
+
+	t.Helper()
+	id, outputs, err := p.Read(r.token, r.name, r.ID, r.Outputs)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if id != r.ID {
+		t.Errorf("read returned ID %q, expected %q", id, r.ID)
+	}
+	if !reflect.DeepEqual(outputs, r.Outputs) {
+		t.Errorf("read returned outputs %v, expected %v", outputs, r.Outputs)
+	}
+
+	checked, err := p.Check(r.token, r.name, r.Inputs, inputs)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	AssertNoFailures(t, checked)
+	diff, err := p.Diff(r.token, r.name, r.ID, r.Outputs, checked.Inputs)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if diff.Changes {
+		t.Errorf("diff of identical inputs reported changes %v", diff.DetailedDiff)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	"github.com/pulumi/pulumi/pkg/v3/resource/provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+	"google.golang.org/grpc"
+)
+
+// LogEntry is a message the provider sent to the engine.
+type LogEntry struct {
+	Severity rpc.LogSeverity
+	URN      resource.URN
+	Message  string
+}
+
+// engine is a fake Pulumi engine that records the messages logged by a provider.
+type engine struct {
+	rpc.UnimplementedEngineServer
+
+	mu   sync.Mutex
+	logs []LogEntry
+	root string
+}
+
+func (e *engine) Log(_ context.Context, req *rpc.LogRequest) (*pbempty.Empty, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logs = append(e.logs, LogEntry{
+		Severity: req.GetSeverity(),
+		URN:      resource.URN(req.GetUrn()),
+		Message:  req.GetMessage(),
+	})
+	return &pbempty.Empty{}, nil
+}
+
+func (e *engine) GetRootResource(context.Context, *rpc.GetRootResourceRequest) (*rpc.GetRootResourceResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &rpc.GetRootResourceResponse{Urn: e.root}, nil
+}
+
+func (e *engine) SetRootResource(_ context.Context, req *rpc.SetRootResourceRequest) (
+	*rpc.SetRootResourceResponse, error) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.root = req.GetUrn()
+	return &rpc.SetRootResourceResponse{}, nil
+}
+
+func (e *engine) entries() []LogEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]LogEntry(nil), e.logs...)
+}
+
+// startEngine serves the fake engine on a loopback port and returns a host client connected to it, along with a
+// function that stops the engine.
+func startEngine(e *engine) (*provider.HostClient, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	server := grpc.NewServer()
+	rpc.RegisterEngineServer(server, e)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	host, err := provider.NewHostClient(listener.Addr().String())
+	if err != nil {
+		server.Stop()
+		return nil, nil, err
+	}
+	return host, func() {
+		_ = host.Close()
+		server.Stop()
+	}, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+)
+
+// Resource is a resource managed through its lifecycle the way the engine would: inputs are checked before every
+// change, and Diff decides between an update and a replacement.
+type Resource struct {
+	p     *Provider
+	token string
+	name  string
+
+	// ID of the resource, empty once it has been deleted.
+	ID string
+	// Inputs are the checked inputs of the last deployment.
+	Inputs map[string]interface{}
+	// Outputs are the current outputs of the resource.
+	Outputs map[string]interface{}
+}
+
+// Up checks the inputs of a new resource and creates it.
+func (p *Provider) Up(token, name string, inputs map[string]interface{}) (*Resource, error) {
+	checked, err := p.Check(token, name, nil, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFailures(checked.Failures); err != nil {
+		return nil, err
+	}
+	id, outputs, err := p.Create(token, name, checked.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	return &Resource{p: p, token: token, name: name, ID: id, Inputs: checked.Inputs, Outputs: outputs}, nil
+}
+
+// Update checks the new inputs and diffs them against the current outputs. The resource is then updated in place or
+// replaced, as the diff requires. The diff is returned so that tests can assert on it.
+func (r *Resource) Update(inputs map[string]interface{}) (DiffResult, error) {
+	checked, err := r.p.Check(r.token, r.name, r.Inputs, inputs)
+	if err != nil {
+		return DiffResult{}, err
+	}
+	if err := checkFailures(checked.Failures); err != nil {
+		return DiffResult{}, err
+	}
+	diff, err := r.p.Diff(r.token, r.name, r.ID, r.Outputs, checked.Inputs)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	switch {
+	case !diff.Changes:
+		r.Inputs = checked.Inputs
+	case len(diff.Replaces) > 0:
+		if diff.DeleteBeforeReplace {
+			if err := r.Delete(); err != nil {
+				return diff, err
+			}
+		}
+		id, outputs, err := r.p.Create(r.token, r.name, checked.Inputs)
+		if err != nil {
+			return diff, err
+		}
+		if !diff.DeleteBeforeReplace {
+			if err := r.Delete(); err != nil {
+				return diff, err
+			}
+		}
+		r.ID, r.Inputs, r.Outputs = id, checked.Inputs, outputs
+	default:
+		outputs, err := r.p.Update(r.token, r.name, r.ID, r.Outputs, checked.Inputs)
+		if err != nil {
+			return diff, err
+		}
+		r.Inputs, r.Outputs = checked.Inputs, outputs
+	}
+	return diff, nil
+}
+
+// Refresh reads the current outputs of the resource. It returns false if the resource no longer exists.
+func (r *Resource) Refresh() (bool, error) {
+	id, outputs, err := r.p.Read(r.token, r.name, r.ID, r.Outputs)
+	if err != nil {
+		return false, err
+	}
+	if id == "" {
+		r.ID, r.Outputs = "", nil
+		return false, nil
+	}
+	r.ID, r.Outputs = id, outputs
+	return true, nil
+}
+
+// Delete deletes the resource.
+func (r *Resource) Delete() error {
+	if err := r.p.Delete(r.token, r.name, r.ID, r.Outputs); err != nil {
+		return err
+	}
+	r.ID, r.Outputs = "", nil
+	return nil
+}
+
+// checkFailures turns validation failures into an error.
+func checkFailures(failures []resources.CheckFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	reasons := make([]string, len(failures))
+	for i, f := range failures {
+		reasons[i] = f.Reason
+	}
+	return fmt.Errorf("invalid inputs: %s", strings.Join(reasons, "; "))
+}
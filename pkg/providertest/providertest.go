@@ -0,0 +1,268 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providertest runs the provider in-process so that resources can be tested from Go without the Pulumi CLI.
+// Requests and responses use plain maps; the provider is reached through its gRPC interface, so values go through the
+// same marshaling as with a real engine.
+package providertest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	xyzprovider "github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+const (
+	stack   = "test"
+	project = "providertest"
+)
+
+// Provider is a provider running in-process against a fake engine.
+type Provider struct {
+	server rpc.ResourceProviderServer
+	engine *engine
+}
+
+// New starts a provider with the given options. It is stopped when the test finishes. Unless the options set an output
+// validation, including OutputValidationOff, requests fail if the outputs of a resource do not match its schema.
+func New(t testing.TB, opts xyzprovider.Options) *Provider {
+	t.Helper()
+
+	if opts.OutputValidation == xyzprovider.OutputValidationDefault {
+		opts.OutputValidation = xyzprovider.OutputValidationError
+	}
+
+	e := &engine{}
+	host, stop, err := startEngine(e)
+	if err != nil {
+		t.Fatalf("starting engine: %v", err)
+	}
+	t.Cleanup(stop)
+
+	server, err := xyzprovider.New(host, opts)
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	return &Provider{server: server, engine: e}
+}
+
+// Server returns the gRPC server of the provider, for requests not covered by the helpers.
+func (p *Provider) Server() rpc.ResourceProviderServer {
+	return p.server
+}
+
+// Logs returns the messages the provider logged so far.
+func (p *Provider) Logs() []LogEntry {
+	return p.engine.entries()
+}
+
+// URN returns the URN of a resource with the given type and name in the test stack.
+func URN(token, name string) resource.URN {
+	return resource.NewURN(stack, project, "", tokens.Type(token), tokens.QName(name))
+}
+
+// Configure configures the provider with the given configuration values.
+func (p *Provider) Configure(config map[string]interface{}) error {
+	args, err := marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = p.server.Configure(context.Background(), &rpc.ConfigureRequest{Args: args})
+	return err
+}
+
+// CheckResult is the result of checking the inputs of a resource.
+type CheckResult struct {
+	// Inputs after defaults and auto-names were applied.
+	Inputs map[string]interface{}
+	// Failures of validation, if any.
+	Failures []resources.CheckFailure
+}
+
+// Check validates the new inputs of a resource. Olds are the inputs of the previous deployment and may be nil.
+func (p *Provider) Check(token, name string, olds, news map[string]interface{}) (CheckResult, error) {
+	oldProps, err := marshal(olds)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	newProps, err := marshal(news)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	resp, err := p.server.Check(context.Background(), &rpc.CheckRequest{
+		Urn:  string(URN(token, name)),
+		Olds: oldProps,
+		News: newProps,
+	})
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	inputs, err := unmarshal(resp.GetInputs())
+	if err != nil {
+		return CheckResult{}, err
+	}
+	var failures []resources.CheckFailure
+	for _, f := range resp.GetFailures() {
+		failures = append(failures, resources.CheckFailure{Property: f.GetProperty(), Reason: f.GetReason()})
+	}
+	return CheckResult{Inputs: inputs, Failures: failures}, nil
+}
+
+// DiffResult is the result of comparing the state of a resource with new inputs.
+type DiffResult struct {
+	// Changes is true if the resource needs an update or a replacement.
+	Changes bool
+	// Replaces lists the changed properties that force a replacement, sorted.
+	Replaces []string
+	// DeleteBeforeReplace is true if the old resource must be deleted before its replacement is created.
+	DeleteBeforeReplace bool
+	// DetailedDiff maps the path of each changed property to the kind of change.
+	DetailedDiff map[string]rpc.PropertyDiff_Kind
+}
+
+// Diff compares the stored outputs of a resource with checked new inputs.
+func (p *Provider) Diff(token, name, id string, olds, news map[string]interface{}) (DiffResult, error) {
+	oldProps, err := marshal(olds)
+	if err != nil {
+		return DiffResult{}, err
+	}
+	newProps, err := marshal(news)
+	if err != nil {
+		return DiffResult{}, err
+	}
+	resp, err := p.server.Diff(context.Background(), &rpc.DiffRequest{
+		Id:   id,
+		Urn:  string(URN(token, name)),
+		Olds: oldProps,
+		News: newProps,
+	})
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	replaces := append([]string(nil), resp.GetReplaces()...)
+	sort.Strings(replaces)
+	detailed := map[string]rpc.PropertyDiff_Kind{}
+	for path, d := range resp.GetDetailedDiff() {
+		detailed[path] = d.GetKind()
+	}
+	return DiffResult{
+		Changes:             resp.GetChanges() == rpc.DiffResponse_DIFF_SOME,
+		Replaces:            replaces,
+		DeleteBeforeReplace: resp.GetDeleteBeforeReplace(),
+		DetailedDiff:        detailed,
+	}, nil
+}
+
+// Create creates a resource from checked inputs and returns its ID and outputs.
+func (p *Provider) Create(token, name string, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+	props, err := marshal(inputs)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := p.server.Create(context.Background(), &rpc.CreateRequest{
+		Urn:        string(URN(token, name)),
+		Properties: props,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	outputs, err := unmarshal(resp.GetProperties())
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.GetId(), outputs, nil
+}
+
+// Read refreshes the outputs of a resource. The ID is empty if the resource no longer exists.
+func (p *Provider) Read(token, name, id string, state map[string]interface{}) (string, map[string]interface{}, error) {
+	props, err := marshal(state)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := p.server.Read(context.Background(), &rpc.ReadRequest{
+		Id:         id,
+		Urn:        string(URN(token, name)),
+		Properties: props,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	outputs, err := unmarshal(resp.GetProperties())
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.GetId(), outputs, nil
+}
+
+// Update updates a resource in place and returns its new outputs.
+func (p *Provider) Update(token, name, id string, olds, news map[string]interface{}) (map[string]interface{}, error) {
+	oldProps, err := marshal(olds)
+	if err != nil {
+		return nil, err
+	}
+	newProps, err := marshal(news)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.server.Update(context.Background(), &rpc.UpdateRequest{
+		Id:   id,
+		Urn:  string(URN(token, name)),
+		Olds: oldProps,
+		News: newProps,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return unmarshal(resp.GetProperties())
+}
+
+// Delete deletes a resource given its stored outputs.
+func (p *Provider) Delete(token, name, id string, state map[string]interface{}) error {
+	props, err := marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = p.server.Delete(context.Background(), &rpc.DeleteRequest{
+		Id:         id,
+		Urn:        string(URN(token, name)),
+		Properties: props,
+	})
+	return err
+}
+
+func marshal(m map[string]interface{}) (*structpb.Struct, error) {
+	return plugin.MarshalProperties(resource.NewPropertyMapFromMap(m), plugin.MarshalOptions{
+		KeepUnknowns: true, KeepSecrets: true,
+	})
+}
+
+func unmarshal(s *structpb.Struct) (map[string]interface{}, error) {
+	props, err := plugin.UnmarshalProperties(s, plugin.MarshalOptions{
+		KeepUnknowns: true, KeepSecrets: true, SkipNulls: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return props.Mappable(), nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+type widgetInputs struct {
+	Size  int    `pulumi:"size,optional" deprecated:"Use width instead."`
+	Token string `pulumi:"token,optional,secret"`
+}
+
+func newWidgetRegistry() *resources.Registry {
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Widget", &resources.CustomResource{
+		Inputs:  widgetInputs{},
+		Outputs: widgetInputs{},
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			return "widget", inputs, nil
+		},
+	})
+	return registry
+}
+
+type switchState string
+
+func (switchState) EnumValues() []schema.EnumValueSpec {
+	return []schema.EnumValueSpec{{Value: "on"}, {Value: "off"}}
+}
+
+type switchInputs struct {
+	State switchState `pulumi:"state,optional" default:"off"`
+	Delay int         `pulumi:"delay,optional" default:"5"`
+}
+
+func TestDefaultsAreApplied(t *testing.T) {
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Switch", &resources.CustomResource{
+		Inputs:  switchInputs{},
+		Outputs: switchInputs{},
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			return "switch", inputs, nil
+		},
+	})
+	p := New(t, provider.Options{Registry: registry})
+
+	r, err := p.Up("test:index:Switch", "s", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertOutputs(t, r.Inputs, map[string]interface{}{"state": "off", "delay": 5})
+	AssertOutputs(t, r.Outputs, map[string]interface{}{"state": "off", "delay": 5})
+
+	diff, err := r.Update(map[string]interface{}{"state": "on"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertDiff(t, diff, map[string]rpc.PropertyDiff_Kind{"state": rpc.PropertyDiff_UPDATE_REPLACE})
+	AssertOutputs(t, r.Outputs, map[string]interface{}{"state": "on", "delay": 5})
+}
+
+type serverInputs struct {
+	Zone string `pulumi:"zone,replaceOnChanges"`
+	Size int    `pulumi:"size"`
+}
+
+func TestReplaceOnChanges(t *testing.T) {
+	var ops []string
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Server", &resources.CustomResource{
+		Inputs:              serverInputs{},
+		Outputs:             serverInputs{},
+		DeleteBeforeReplace: true,
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			ops = append(ops, "create")
+			return inputs["zone"].(string), inputs, nil
+		},
+		Update: func(_ context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+			ops = append(ops, "update")
+			return inputs, nil
+		},
+		Delete: func(context.Context, map[string]interface{}) error {
+			ops = append(ops, "delete")
+			return nil
+		},
+	})
+	p := New(t, provider.Options{Registry: registry})
+
+	r, err := p.Up("test:index:Server", "s", map[string]interface{}{"zone": "a", "size": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Other properties are updated in place.
+	diff, err := r.Update(map[string]interface{}{"zone": "a", "size": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertDiff(t, diff, map[string]rpc.PropertyDiff_Kind{"size": rpc.PropertyDiff_UPDATE})
+	if len(diff.Replaces) != 0 || diff.DeleteBeforeReplace {
+		t.Errorf("expected an update in place, got replaces %v and deleteBeforeReplace %v",
+			diff.Replaces, diff.DeleteBeforeReplace)
+	}
+
+	diff, err = r.Update(map[string]interface{}{"zone": "b", "size": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertDiff(t, diff, map[string]rpc.PropertyDiff_Kind{"zone": rpc.PropertyDiff_UPDATE_REPLACE})
+	if !reflect.DeepEqual(diff.Replaces, []string{"zone"}) || !diff.DeleteBeforeReplace {
+		t.Errorf("expected zone to replace the resource after deleting it, got replaces %v and deleteBeforeReplace %v",
+			diff.Replaces, diff.DeleteBeforeReplace)
+	}
+	if expected := []string{"create", "update", "delete", "create"}; !reflect.DeepEqual(ops, expected) {
+		t.Errorf("expected the operations %v, got %v", expected, ops)
+	}
+	if r.ID != "b" {
+		t.Errorf("expected the replacement to have ID b, got %q", r.ID)
+	}
+}
+
+func TestLogsAreCaptured(t *testing.T) {
+	p := New(t, provider.Options{Registry: newWidgetRegistry()})
+
+	result, err := p.Check("test:index:Widget", "w", nil, map[string]interface{}{"size": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertNoFailures(t, result)
+	AssertLogged(t, p, rpc.LogSeverity_WARNING, "Use width instead.")
+}
+
+func TestOutputsAreValidated(t *testing.T) {
+	registry := newWidgetRegistry()
+	registry.MustRegister("test:index:Gadget", &resources.CustomResource{
+		Inputs:  widgetInputs{},
+		Outputs: widgetInputs{},
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			return "gadget", map[string]interface{}{"size": "large", "color": "red"}, nil
+		},
+	})
+	p := New(t, provider.Options{Registry: registry})
+
+	_, err := p.Up("test:index:Gadget", "g", map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), `output "color" is not a property`) ||
+		!strings.Contains(err.Error(), `output "size" should be of type integer`) {
+		t.Errorf("expected mismatched outputs to fail, got %v", err)
+	}
+	if _, err := p.Up("test:index:Widget", "w", map[string]interface{}{"size": 3}); err != nil {
+		t.Errorf("expected matching outputs to pass, got %v", err)
+	}
+}
+
+func TestOutputValidationCanBeTurnedOff(t *testing.T) {
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Gadget", &resources.CustomResource{
+		Inputs:  widgetInputs{},
+		Outputs: widgetInputs{},
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			return "gadget", map[string]interface{}{"color": "red"}, nil
+		},
+	})
+	p := New(t, provider.Options{Registry: registry, OutputValidation: provider.OutputValidationOff})
+
+	if _, err := p.Up("test:index:Gadget", "g", map[string]interface{}{}); err != nil {
+		t.Errorf("expected outputs not to be validated, got %v", err)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	registry := newWidgetRegistry()
+	recorder := provider.Recorder(f, provider.NewRedactor(registry, schema.ConfigSpec{}))
+	p := New(t, provider.Options{Registry: registry, Middlewares: []provider.Middleware{recorder}})
+	if _, err := p.Up("test:index:Widget", "w", map[string]interface{}{"size": 3, "token": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(recording), "hunter2") {
+		t.Errorf("expected secrets to be redacted, got %s", recording)
+	}
+	Replay(t, provider.Options{Registry: registry}, path)
+
+	// Responses that differ from the recording are reported.
+	interactions, err := ReadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := &interactions[len(interactions)-1]
+	create.Response = json.RawMessage(strings.Replace(string(create.Response), `"size":3`, `"size":4`, 1))
+	errs := replay(New(t, provider.Options{Registry: registry}), provider.NewRedactor(registry, schema.ConfigSpec{}),
+		interactions, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "(Create)") {
+		t.Errorf("expected the changed Create response to be reported, got %v", errs)
+	}
+}
+
+type credentials struct {
+	User     string `pulumi:"user"`
+	Password string `pulumi:"password,secret"`
+}
+
+type databaseInputs struct {
+	Name        string        `pulumi:"name"`
+	Credentials []credentials `pulumi:"credentials"`
+}
+
+func TestRecordingsOmitSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The secret leaks into a check failure and an error, and as a wire secret into a property that is not secret.
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Database", &resources.CustomResource{
+		Inputs:  databaseInputs{},
+		Outputs: databaseInputs{},
+		Check: func(_ context.Context, _, news map[string]interface{}) (
+			map[string]interface{}, []resources.CheckFailure, error) {
+
+			password := news["credentials"].([]interface{})[0].(map[string]interface{})["password"]
+			return nil, []resources.CheckFailure{{Property: "name", Reason: fmt.Sprintf("%v is weak", password)}}, nil
+		},
+		Create: func(_ context.Context, inputs map[string]interface{}) (string, map[string]interface{}, error) {
+			password := inputs["credentials"].([]interface{})[0].(map[string]interface{})["password"]
+			return "", nil, fmt.Errorf("login with %v failed", password)
+		},
+	})
+	recorder := provider.Recorder(f, provider.NewRedactor(registry, schema.ConfigSpec{}))
+	p := New(t, provider.Options{Registry: registry, Middlewares: []provider.Middleware{recorder}})
+
+	inputs := map[string]interface{}{
+		"name":        &resource.Secret{Element: resource.NewStringProperty("db-hunter3")},
+		"credentials": []interface{}{map[string]interface{}{"user": "admin", "password": "hunter2"}},
+	}
+	if _, err := p.Check("test:index:Database", "db", nil, inputs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Create("test:index:Database", "db", inputs); err == nil {
+		t.Fatal("expected the creation to fail")
+	}
+
+	recording, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(recording), "admin") {
+		t.Errorf("expected values that are not secret to be recorded, got %s", recording)
+	}
+	if strings.Contains(string(recording), "hunter2") || strings.Contains(string(recording), "hunter3") {
+		t.Errorf("expected secrets to be redacted, got %s", recording)
+	}
+}
+
+func TestRecordingsKeepURNs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The secret is also the name of the resource and part of its ID.
+	registry := newWidgetRegistry()
+	recorder := provider.Recorder(f, provider.NewRedactor(registry, schema.ConfigSpec{}))
+	p := New(t, provider.Options{Registry: registry, Middlewares: []provider.Middleware{recorder}})
+	if _, err := p.Up("test:index:Widget", "w", map[string]interface{}{"size": 3, "token": "w"}); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urn := URN("test:index:Widget", "w")
+	if !strings.Contains(string(recording), string(urn)) || !strings.Contains(string(recording), `"id":"widget"`) {
+		t.Errorf("expected the URN %s and the ID to be recorded, got %s", urn, recording)
+	}
+	if strings.Contains(string(recording), `"token":"w"`) {
+		t.Errorf("expected the secret to be redacted, got %s", recording)
+	}
+	Replay(t, provider.Options{Registry: registry}, path)
+}
@@ -0,0 +1,251 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+	xyzprovider "github.com/pulumi/pulumi-xyz/pkg/provider"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+// Replay feeds the requests of a recording written by provider.Recorder into a fresh provider created with the
+// given options, in order, and fails the test for every response that differs from the recorded one. Properties named
+// in ignore, e.g. randomly generated outputs, are not compared. Secrets were redacted when recording, so requests
+// carry provider.Redacted in their place.
+func Replay(t *testing.T, opts xyzprovider.Options, path string, ignore ...string) {
+	t.Helper()
+
+	interactions, err := ReadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range replay(New(t, opts), xyzprovider.NewRedactor(opts.Registry, opts.Config), interactions,
+		ignore) {
+		t.Error(err)
+	}
+}
+
+// replay sends the recorded requests to the provider and returns an error for each response that differs.
+func replay(p *Provider, redactor *xyzprovider.Redactor, interactions []xyzprovider.Interaction,
+	ignore []string) []error {
+
+	ignored := map[string]bool{}
+	for _, name := range ignore {
+		ignored[name] = true
+	}
+
+	var errs []error
+	for i, recorded := range interactions {
+		req, resp, err := call(p.server, recorded.Method, recorded.Request)
+		if err != nil && req == nil {
+			errs = append(errs, fmt.Errorf("interaction %d: %w", i+1, err))
+			continue
+		}
+		actual, err := redactor.Interaction(recorded.Method, req, resp, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("interaction %d: %w", i+1, err))
+			continue
+		}
+
+		if actual.Error != recorded.Error {
+			errs = append(errs, fmt.Errorf("interaction %d (%s): expected error %q, got %q", i+1, recorded.Method,
+				recorded.Error, actual.Error))
+			continue
+		}
+		if equal, err := equalJSON(recorded.Response, actual.Response, ignored); err != nil {
+			errs = append(errs, fmt.Errorf("interaction %d: %w", i+1, err))
+		} else if !equal {
+			errs = append(errs, fmt.Errorf("interaction %d (%s): expected response %s, got %s", i+1,
+				recorded.Method, recorded.Response, actual.Response))
+		}
+	}
+	return errs
+}
+
+// ReadRecording reads the interactions of a recording.
+func ReadRecording(path string) ([]xyzprovider.Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var interactions []xyzprovider.Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var interaction xyzprovider.Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, scanner.Err()
+}
+
+// call decodes a recorded request and sends it to the provider. The request is nil if it could not be decoded.
+func call(server rpc.ResourceProviderServer, method string, raw json.RawMessage) (proto.Message, proto.Message,
+	error) {
+
+	ctx := context.Background()
+	decode := func(req proto.Message) error {
+		return jsonpb.Unmarshal(bytes.NewReader(raw), req)
+	}
+
+	switch method {
+	case "GetSchema":
+		req := &rpc.GetSchemaRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.GetSchema(ctx, req)
+		return req, resp, err
+	case "CheckConfig", "Check":
+		req := &rpc.CheckRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		check := server.Check
+		if method == "CheckConfig" {
+			check = server.CheckConfig
+		}
+		resp, err := check(ctx, req)
+		return req, resp, err
+	case "DiffConfig", "Diff":
+		req := &rpc.DiffRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		diff := server.Diff
+		if method == "DiffConfig" {
+			diff = server.DiffConfig
+		}
+		resp, err := diff(ctx, req)
+		return req, resp, err
+	case "Configure":
+		req := &rpc.ConfigureRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Configure(ctx, req)
+		return req, resp, err
+	case "Invoke":
+		req := &rpc.InvokeRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Invoke(ctx, req)
+		return req, resp, err
+	case "Create":
+		req := &rpc.CreateRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Create(ctx, req)
+		return req, resp, err
+	case "Read":
+		req := &rpc.ReadRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Read(ctx, req)
+		return req, resp, err
+	case "Update":
+		req := &rpc.UpdateRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Update(ctx, req)
+		return req, resp, err
+	case "Delete":
+		req := &rpc.DeleteRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Delete(ctx, req)
+		return req, resp, err
+	case "Construct":
+		req := &rpc.ConstructRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Construct(ctx, req)
+		return req, resp, err
+	case "Call":
+		req := &rpc.CallRequest{}
+		if err := decode(req); err != nil {
+			return nil, nil, err
+		}
+		resp, err := server.Call(ctx, req)
+		return req, resp, err
+	case "Cancel":
+		req := &pbempty.Empty{}
+		resp, err := server.Cancel(ctx, req)
+		return req, resp, err
+	case "GetPluginInfo":
+		req := &pbempty.Empty{}
+		resp, err := server.GetPluginInfo(ctx, req)
+		return req, resp, err
+	default:
+		return nil, nil, fmt.Errorf("unknown method %q", method)
+	}
+}
+
+// equalJSON compares two JSON documents, skipping object keys that are ignored.
+func equalJSON(expected, actual json.RawMessage, ignored map[string]bool) (bool, error) {
+	if len(expected) == 0 || len(actual) == 0 {
+		return len(expected) == len(actual), nil
+	}
+	var e, a interface{}
+	if err := json.Unmarshal(expected, &e); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(withoutKeys(e, ignored), withoutKeys(a, ignored)), nil
+}
+
+func withoutKeys(v interface{}, ignored map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range v {
+			if ignored[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = withoutKeys(elem, ignored)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = withoutKeys(elem, ignored)
+		}
+	}
+	return v
+}
@@ -0,0 +1,31 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import "context"
+
+type configKey struct{}
+
+// WithConfig returns a context that carries the provider configuration to resource functions.
+func WithConfig(ctx context.Context, config map[string]interface{}) context.Context {
+	return context.WithValue(ctx, configKey{}, config)
+}
+
+// Config returns the provider configuration passed to resource functions, keyed by variable name. It returns nil
+// if the provider has not been configured.
+func Config(ctx context.Context) map[string]interface{} {
+	config, _ := ctx.Value(configKey{}).(map[string]interface{})
+	return config
+}
@@ -0,0 +1,31 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/providertest"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+)
+
+func TestConformance(t *testing.T) {
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	providertest.Conformance(t, provider.Options{Registry: registry})
+}
@@ -0,0 +1,210 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint restricts the values of an input property. The provider enforces constraints in Check, and the code
+// generator renders them into the property descriptions of the SDKs. Unset fields impose no restriction.
+type Constraint struct {
+	// Inclusive lower and upper bounds of a numeric value.
+	Min, Max *float64
+	// Inclusive lower and upper bounds of the length of a string or array value.
+	MinLength, MaxLength *int
+	// Regular expression that a string value must match.
+	Pattern string
+	// Properties that must not be set together with this one.
+	ConflictsWith []string
+	// Group of properties, including this one, of which exactly one must be set.
+	ExactlyOneOf []string
+	// Properties that must be set whenever this one is set.
+	RequiredWith []string
+
+	// Pattern compiled when the resource is registered.
+	pattern *regexp.Regexp
+}
+
+// Matches reports whether a string value matches the Pattern of the constraint. Patterns are compiled when the
+// resource is registered; constraints without a pattern match any value.
+func (c Constraint) Matches(value string) bool {
+	return c.pattern == nil || c.pattern.MatchString(value)
+}
+
+// Float returns a pointer to the given value, for use in Constraint literals.
+func Float(v float64) *float64 { return &v }
+
+// Int returns a pointer to the given value, for use in Constraint literals.
+func Int(v int) *int { return &v }
+
+// Describe renders the constraint as sentences suitable for a property description.
+func (c Constraint) Describe() string {
+	var sentences []string
+	switch {
+	case c.Min != nil && c.Max != nil:
+		sentences = append(sentences, fmt.Sprintf("Must be between %v and %v.", *c.Min, *c.Max))
+	case c.Min != nil:
+		sentences = append(sentences, fmt.Sprintf("Must be at least %v.", *c.Min))
+	case c.Max != nil:
+		sentences = append(sentences, fmt.Sprintf("Must be at most %v.", *c.Max))
+	}
+	switch {
+	case c.MinLength != nil && c.MaxLength != nil:
+		sentences = append(sentences, fmt.Sprintf("Length must be between %d and %d.", *c.MinLength, *c.MaxLength))
+	case c.MinLength != nil:
+		sentences = append(sentences, fmt.Sprintf("Length must be at least %d.", *c.MinLength))
+	case c.MaxLength != nil:
+		sentences = append(sentences, fmt.Sprintf("Length must be at most %d.", *c.MaxLength))
+	}
+	if c.Pattern != "" {
+		sentences = append(sentences, fmt.Sprintf("Must match the regular expression `%s`.", c.Pattern))
+	}
+	if len(c.ConflictsWith) > 0 {
+		sentences = append(sentences, fmt.Sprintf("Conflicts with %s.", codeList(c.ConflictsWith)))
+	}
+	if len(c.ExactlyOneOf) > 0 {
+		sentences = append(sentences, fmt.Sprintf("Exactly one of %s must be set.", codeList(c.ExactlyOneOf)))
+	}
+	if len(c.RequiredWith) > 0 {
+		sentences = append(sentences, fmt.Sprintf("Requires %s to be set.", codeList(c.RequiredWith)))
+	}
+	return strings.Join(sentences, " ")
+}
+
+func codeList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "`" + name + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// validateInputAnnotations checks that constraints, replaceOnChanges and auto-naming annotations refer to declared
+// input properties, and compiles constraint patterns.
+func (r *CustomResource) validateInputAnnotations(token string) error {
+	if r.AutoName != nil {
+		prop, ok := r.Schema.InputProperties[r.AutoName.Property]
+		if !ok || prop.Type != "string" {
+			return fmt.Errorf("auto-named property %q of %q must be a string input", r.AutoName.Property, token)
+		}
+		for _, name := range r.Schema.RequiredInputs {
+			if name == r.AutoName.Property {
+				return fmt.Errorf("auto-named property %q of %q must not be required", name, token)
+			}
+		}
+	}
+	for _, name := range r.ReplaceOnChanges {
+		if _, ok := r.Schema.InputProperties[name]; !ok {
+			return fmt.Errorf("replaceOnChanges of %q refers to unknown input property %q", token, name)
+		}
+	}
+
+	names := make([]string, 0, len(r.Constraints))
+	for name := range r.Constraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := r.Constraints[name]
+		refs := append([]string{name}, c.ConflictsWith...)
+		refs = append(refs, c.ExactlyOneOf...)
+		refs = append(refs, c.RequiredWith...)
+		for _, ref := range refs {
+			if _, ok := r.Schema.InputProperties[ref]; !ok {
+				return fmt.Errorf("constraint of %q in %q refers to unknown input property %q", name, token, ref)
+			}
+		}
+		if c.Pattern != "" {
+			pattern, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("constraint of %q in %q has an invalid pattern: %w", name, token, err)
+			}
+			c.pattern = pattern
+			r.Constraints[name] = c
+		}
+	}
+	return nil
+}
+
+// inferConstraints reads constraints from the `min`, `max`, `minLength`, `maxLength`, `pattern`, `conflictsWith`,
+// `exactlyOneOf` and `requiredWith` tags of the top-level fields of an input struct. List tags are comma-separated.
+func inferConstraints(t reflect.Type) (map[string]Constraint, error) {
+	constraints := map[string]Constraint{}
+	for _, f := range taggedFields(t) {
+		field := f.field
+		var c Constraint
+		var err error
+		if c.Min, err = floatTag(field, "min"); err != nil {
+			return nil, err
+		}
+		if c.Max, err = floatTag(field, "max"); err != nil {
+			return nil, err
+		}
+		if c.MinLength, err = intTag(field, "minLength"); err != nil {
+			return nil, err
+		}
+		if c.MaxLength, err = intTag(field, "maxLength"); err != nil {
+			return nil, err
+		}
+		c.Pattern = field.Tag.Get("pattern")
+		c.ConflictsWith = listTag(field, "conflictsWith")
+		c.ExactlyOneOf = listTag(field, "exactlyOneOf")
+		c.RequiredWith = listTag(field, "requiredWith")
+
+		if !reflect.DeepEqual(c, Constraint{}) {
+			constraints[f.tag.name] = c
+		}
+	}
+	return constraints, nil
+}
+
+func floatTag(field reflect.StructField, key string) (*float64, error) {
+	value, ok := field.Tag.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag of field %s: %w", key, field.Name, err)
+	}
+	return &f, nil
+}
+
+func intTag(field reflect.StructField, key string) (*int, error) {
+	value, ok := field.Tag.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag of field %s: %w", key, field.Name, err)
+	}
+	return &n, nil
+}
+
+func listTag(field reflect.StructField, key string) []string {
+	value, ok := field.Tag.Lookup(key)
+	if !ok || value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,549 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Annotated is implemented by input, output and nested object structs that describe their fields in code rather
+// than in `description` struct tags, which keeps long descriptions readable.
+type Annotated interface {
+	Annotate(a *Annotator)
+}
+
+// Enum is implemented by named Go types whose values are restricted to a fixed set. Properties of such types reference
+// an enum type of the package, so SDKs get typed enum constants and the provider rejects any other value in Check.
+type Enum interface {
+	EnumValues() []schema.EnumValueSpec
+}
+
+var enumType = reflect.TypeOf((*Enum)(nil)).Elem()
+
+// Annotator collects descriptions of a struct and its fields from an Annotate method.
+type Annotator struct {
+	base         reflect.Value
+	description  string
+	descriptions map[uintptr]string
+}
+
+// Describe sets the description of the struct itself.
+func (a *Annotator) Describe(description string) {
+	a.description = description
+}
+
+// DescribeField sets the description of a field. The field must be passed as a pointer into the annotated struct,
+// e.g. `a.DescribeField(&args.Length, "Length of the string.")`.
+func (a *Annotator) DescribeField(field interface{}, description string) {
+	ptr := reflect.ValueOf(field)
+	if ptr.Kind() != reflect.Ptr {
+		panic("DescribeField expects a pointer to a struct field")
+	}
+	a.descriptions[ptr.Pointer()-a.base.Pointer()] = description
+}
+
+// annotate calls the Annotate method of the given struct type, if any.
+func annotate(t reflect.Type) *Annotator {
+	v := reflect.New(t)
+	a := &Annotator{base: v, descriptions: map[uintptr]string{}}
+	if annotated, ok := v.Interface().(Annotated); ok {
+		annotated.Annotate(a)
+	}
+	return a
+}
+
+// fieldTag is the parsed form of a `pulumi:"name,option,..."` struct tag.
+type fieldTag struct {
+	name             string
+	optional         bool
+	secret           bool
+	replaceOnChanges bool
+}
+
+func parseFieldTag(field reflect.StructField) (fieldTag, bool) {
+	tag, ok := field.Tag.Lookup("pulumi")
+	if !ok {
+		return fieldTag{}, false
+	}
+	parts := strings.Split(tag, ",")
+	result := fieldTag{name: parts[0]}
+	for _, option := range parts[1:] {
+		switch option {
+		case "optional":
+			result.optional = true
+		case "secret":
+			result.secret = true
+		case "replaceOnChanges":
+			result.replaceOnChanges = true
+		}
+	}
+	return result, result.name != "" && result.name != "-"
+}
+
+type taggedField struct {
+	field reflect.StructField
+	tag   fieldTag
+}
+
+// taggedFields returns the fields of a struct type that have a `pulumi` tag, including those of embedded structs.
+func taggedFields(t reflect.Type) []taggedField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, ok := parseFieldTag(field); ok {
+			fields = append(fields, taggedField{field: field, tag: tag})
+		} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, taggedFields(field.Type)...)
+		}
+	}
+	return fields
+}
+
+// schemaInferrer derives schema types from Go types. Nested object types are collected into types.
+type schemaInferrer struct {
+	pkg    string
+	module string
+	types  map[string]schema.ComplexTypeSpec
+	goType map[string]reflect.Type
+}
+
+func newSchemaInferrer(token string) (*schemaInferrer, error) {
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid resource token %q", token)
+	}
+	return &schemaInferrer{
+		pkg:    parts[0],
+		module: parts[1],
+		types:  map[string]schema.ComplexTypeSpec{},
+		goType: map[string]reflect.Type{},
+	}, nil
+}
+
+// object infers an object type from a struct type. Unexported fields and fields tagged `pulumi:"-"` are ignored, other
+// exported fields must have a `pulumi` tag. Anonymous struct fields are flattened into the parent object.
+func (inf *schemaInferrer) object(t reflect.Type) (schema.ObjectTypeSpec, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return schema.ObjectTypeSpec{}, fmt.Errorf("expected a struct type but got %v", t)
+	}
+
+	a := annotate(t)
+	spec := schema.ObjectTypeSpec{
+		Description: a.description,
+		Type:        "object",
+		Properties:  map[string]schema.PropertySpec{},
+	}
+	if err := inf.fields(t, 0, a, &spec); err != nil {
+		return schema.ObjectTypeSpec{}, err
+	}
+	sort.Strings(spec.Required)
+	return spec, nil
+}
+
+func (inf *schemaInferrer) fields(t reflect.Type, offset uintptr, a *Annotator, spec *schema.ObjectTypeSpec) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok {
+			_, tagged := field.Tag.Lookup("pulumi")
+			switch {
+			case field.Anonymous && field.Type.Kind() == reflect.Struct:
+				if err := inf.fields(field.Type, offset+field.Offset, a, spec); err != nil {
+					return err
+				}
+			case !tagged && field.PkgPath == "":
+				return fmt.Errorf("exported field %s of %v has no pulumi tag, tag it with `pulumi:\"-\"` to ignore it",
+					field.Name, t)
+			}
+			continue
+		}
+		if _, exists := spec.Properties[tag.name]; exists {
+			return fmt.Errorf("duplicate property %q in %v", tag.name, t)
+		}
+
+		typ, err := inf.typeSpec(field.Type)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", tag.name, err)
+		}
+		description, ok := field.Tag.Lookup("description")
+		if !ok {
+			description = a.descriptions[offset+field.Offset]
+		}
+		prop := schema.PropertySpec{
+			TypeSpec:           typ,
+			Description:        description,
+			DeprecationMessage: field.Tag.Get("deprecated"),
+			Secret:             tag.secret,
+		}
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if prop.Default, err = inf.defaultValue(typ, def); err != nil {
+				return fmt.Errorf("property %q: %w", tag.name, err)
+			}
+		}
+		if env, ok := field.Tag.Lookup("env"); ok {
+			prop.DefaultInfo = &schema.DefaultSpec{Environment: strings.Split(env, ",")}
+		}
+		spec.Properties[tag.name] = prop
+		if !tag.optional && field.Type.Kind() != reflect.Ptr {
+			spec.Required = append(spec.Required, tag.name)
+		}
+	}
+	return nil
+}
+
+// defaultValue parses the value of a `default:"..."` struct tag according to the property type. The default of an enum
+// property must be one of the enum values.
+func (inf *schemaInferrer) defaultValue(typ schema.TypeSpec, value string) (interface{}, error) {
+	if typ.Ref == "" {
+		return parseDefaultTag(typ.Type, value)
+	}
+	enum, ok := inf.types[strings.TrimPrefix(typ.Ref, "#/types/")]
+	if !ok || len(enum.Enum) == 0 {
+		return nil, fmt.Errorf("default values are only supported for primitive and enum types")
+	}
+	def, err := parseDefaultTag(enum.Type, value)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range enum.Enum {
+		if fmt.Sprint(e.Value) == fmt.Sprint(def) {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("default value %q is not a value of the enum type", value)
+}
+
+// parseDefaultTag parses a default value of a primitive type. Integers are returned as float64, like numbers in a
+// schema read from JSON.
+func parseDefaultTag(typ string, value string) (interface{}, error) {
+	switch typ {
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		return float64(n), err
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "string":
+		return value, nil
+	default:
+		return nil, fmt.Errorf("default values are only supported for primitive types")
+	}
+}
+
+// typeSpec infers a type reference from a Go type.
+func (inf *schemaInferrer) typeSpec(t reflect.Type) (schema.TypeSpec, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() != "" && t.Implements(enumType) {
+		return inf.enumRef(t)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return schema.TypeSpec{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema.TypeSpec{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return schema.TypeSpec{Type: "number"}, nil
+	case reflect.String:
+		return schema.TypeSpec{Type: "string"}, nil
+	case reflect.Interface:
+		return schema.TypeSpec{Ref: "pulumi.json#/Any"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := inf.typeSpec(t.Elem())
+		if err != nil {
+			return schema.TypeSpec{}, err
+		}
+		return schema.TypeSpec{Type: "array", Items: &items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return schema.TypeSpec{}, fmt.Errorf("map keys must be strings but got %v", t.Key())
+		}
+		elem, err := inf.typeSpec(t.Elem())
+		if err != nil {
+			return schema.TypeSpec{}, err
+		}
+		return schema.TypeSpec{Type: "object", AdditionalProperties: &elem}, nil
+	case reflect.Struct:
+		return inf.objectRef(t)
+	default:
+		return schema.TypeSpec{}, fmt.Errorf("unsupported type %v", t)
+	}
+}
+
+// objectRef registers a named struct type as an auxiliary object type and returns a reference to it.
+func (inf *schemaInferrer) objectRef(t reflect.Type) (schema.TypeSpec, error) {
+	if t.Name() == "" {
+		return schema.TypeSpec{}, fmt.Errorf("anonymous struct types are not supported")
+	}
+	token := fmt.Sprintf("%s:%s:%s", inf.pkg, inf.module, upperFirst(t.Name()))
+	ref := schema.TypeSpec{Ref: "#/types/" + token}
+	if existing, ok := inf.goType[token]; ok {
+		if existing != t {
+			return schema.TypeSpec{}, fmt.Errorf("types %v and %v both map to %q", existing, t, token)
+		}
+		return ref, nil
+	}
+
+	// Register the type before inferring its fields to allow recursive types.
+	inf.goType[token] = t
+	obj, err := inf.object(t)
+	if err != nil {
+		return schema.TypeSpec{}, err
+	}
+	inf.types[token] = schema.ComplexTypeSpec{ObjectTypeSpec: obj}
+	return ref, nil
+}
+
+// upperFirst returns the name with its first letter in upper case, as type names in tokens are capitalized.
+func upperFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// enumRef registers a named type implementing Enum as an auxiliary enum type and returns a reference to it.
+func (inf *schemaInferrer) enumRef(t reflect.Type) (schema.TypeSpec, error) {
+	token := fmt.Sprintf("%s:%s:%s", inf.pkg, inf.module, upperFirst(t.Name()))
+	ref := schema.TypeSpec{Ref: "#/types/" + token}
+	if existing, ok := inf.goType[token]; ok {
+		if existing != t {
+			return schema.TypeSpec{}, fmt.Errorf("types %v and %v both map to %q", existing, t, token)
+		}
+		return ref, nil
+	}
+
+	var underlying string
+	switch t.Kind() {
+	case reflect.String:
+		underlying = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		underlying = "integer"
+	case reflect.Float32, reflect.Float64:
+		underlying = "number"
+	case reflect.Bool:
+		underlying = "boolean"
+	default:
+		return schema.TypeSpec{}, fmt.Errorf("enum type %v must be a string, integer, number or boolean", t)
+	}
+
+	a := annotate(t)
+	values := reflect.Zero(t).Interface().(Enum).EnumValues()
+	if len(values) == 0 {
+		return schema.TypeSpec{}, fmt.Errorf("enum type %v has no values", t)
+	}
+	inf.goType[token] = t
+	inf.types[token] = schema.ComplexTypeSpec{
+		ObjectTypeSpec: schema.ObjectTypeSpec{Description: a.description, Type: underlying},
+		Enum:           values,
+	}
+	return ref, nil
+}
+
+// inferResourceSchema derives a resource schema and its auxiliary types from input and output structs.
+func inferResourceSchema(token, description string, inputs, outputs interface{}) (
+	*schema.ResourceSpec, map[string]schema.ComplexTypeSpec, error) {
+
+	inf, err := newSchemaInferrer(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	in, err := inf.object(reflect.TypeOf(inputs))
+	if err != nil {
+		return nil, nil, fmt.Errorf("inputs: %w", err)
+	}
+	out, err := inf.object(reflect.TypeOf(outputs))
+	if err != nil {
+		return nil, nil, fmt.Errorf("outputs: %w", err)
+	}
+	if description == "" {
+		description = out.Description
+	}
+	out.Description = description
+
+	return &schema.ResourceSpec{
+		ObjectTypeSpec:  out,
+		InputProperties: in.Properties,
+		RequiredInputs:  in.Required,
+	}, inf.types, nil
+}
+
+// InferSchema derives the resource schema and constraints from the Inputs and Outputs structs, if they are set.
+// Resources without a hand-written Schema get the inferred one; for resources that have both, InferSchema returns an
+// error describing every place where the hand-written schema and the structs disagree. Constraints and
+// replaceOnChanges annotations are checked to refer to declared input properties.
+//
+// InferSchema fills in the Schema, Types, Constraints and ReplaceOnChanges fields of the resource. Once it succeeded,
+// calling it again with the same token does nothing, so the same resource can be registered with several registries.
+// The inferred type tokens depend on the module of the token, so other tokens are rejected.
+func (r *CustomResource) InferSchema(token string) error {
+	if r.inferredFor != "" {
+		if r.inferredFor != token {
+			return fmt.Errorf("schema of %q was already inferred for %q", token, r.inferredFor)
+		}
+		return nil
+	}
+	if r.Inputs == nil && r.Outputs == nil {
+		if r.Schema == nil {
+			return fmt.Errorf("resource %q has neither a schema nor input and output types", token)
+		}
+		return r.validateInputAnnotations(token)
+	}
+	if r.Inputs == nil || r.Outputs == nil {
+		return fmt.Errorf("resource %q must define both Inputs and Outputs to infer its schema", token)
+	}
+
+	spec, types, err := inferResourceSchema(token, r.Description, r.Inputs, r.Outputs)
+	if err != nil {
+		return fmt.Errorf("inferring schema of %q: %w", token, err)
+	}
+	spec.DeprecationMessage = r.DeprecationMessage
+
+	if r.Schema == nil {
+		r.Schema = spec
+	} else if problems := compareResourceSpecs(r.Schema, spec); len(problems) > 0 {
+		return fmt.Errorf("schema of %q does not match its input and output types:\n  %s",
+			token, strings.Join(problems, "\n  "))
+	}
+
+	if r.Types == nil {
+		r.Types = map[string]schema.ComplexTypeSpec{}
+	}
+	for tok, typ := range types {
+		existing, ok := r.Types[tok]
+		if !ok {
+			r.Types[tok] = typ
+			continue
+		}
+		if problems := compareObjects("type "+tok, existing.ObjectTypeSpec, typ.ObjectTypeSpec); len(problems) > 0 {
+			return fmt.Errorf("type %q does not match its Go type:\n  %s", tok, strings.Join(problems, "\n  "))
+		}
+	}
+
+	constraints, err := inferConstraints(reflect.TypeOf(r.Inputs))
+	if err != nil {
+		return fmt.Errorf("inferring constraints of %q: %w", token, err)
+	}
+	if r.Constraints == nil {
+		r.Constraints = map[string]Constraint{}
+	}
+	for name, c := range constraints {
+		existing, ok := r.Constraints[name]
+		existing.pattern = nil
+		if ok && !reflect.DeepEqual(existing, c) {
+			return fmt.Errorf("constraint of %q in %q is declared both in Constraints and in struct tags", name, token)
+		}
+		r.Constraints[name] = c
+	}
+
+	for _, f := range taggedFields(reflect.TypeOf(r.Inputs)) {
+		if f.tag.replaceOnChanges && !r.ForcesReplacement(f.tag.name) {
+			r.ReplaceOnChanges = append(r.ReplaceOnChanges, f.tag.name)
+		}
+	}
+	if err := r.validateInputAnnotations(token); err != nil {
+		return err
+	}
+	r.inferredFor = token
+	return nil
+}
+
+// compareResourceSpecs compares the shape of a declared resource schema with an inferred one. Descriptions are
+// ignored.
+func compareResourceSpecs(declared, inferred *schema.ResourceSpec) []string {
+	problems := compareObjects("output", declared.ObjectTypeSpec, inferred.ObjectTypeSpec)
+	problems = append(problems, compareObjects("input",
+		schema.ObjectTypeSpec{Properties: declared.InputProperties, Required: declared.RequiredInputs},
+		schema.ObjectTypeSpec{Properties: inferred.InputProperties, Required: inferred.RequiredInputs})...)
+	return problems
+}
+
+func compareObjects(kind string, declared, inferred schema.ObjectTypeSpec) []string {
+	var problems []string
+	for _, name := range sortedPropertyNames(declared.Properties, inferred.Properties) {
+		d, inDeclared := declared.Properties[name]
+		i, inInferred := inferred.Properties[name]
+		switch {
+		case !inInferred:
+			problems = append(problems, fmt.Sprintf("%s property %q is missing from the Go type", kind, name))
+		case !inDeclared:
+			problems = append(problems, fmt.Sprintf("%s property %q is missing from the schema", kind, name))
+		case !reflect.DeepEqual(d.TypeSpec, i.TypeSpec):
+			problems = append(problems, fmt.Sprintf("%s property %q has type %s in the schema but %s in the Go type",
+				kind, name, typeString(d.TypeSpec), typeString(i.TypeSpec)))
+		case d.Secret != i.Secret:
+			problems = append(problems, fmt.Sprintf("%s property %q differs in secretness", kind, name))
+		}
+	}
+
+	required := map[string]bool{}
+	for _, name := range declared.Required {
+		required[name] = true
+	}
+	for _, name := range inferred.Required {
+		if !required[name] {
+			problems = append(problems, fmt.Sprintf("%s property %q is required in the Go type only", kind, name))
+		}
+		delete(required, name)
+	}
+	for _, name := range declared.Required {
+		if required[name] {
+			problems = append(problems, fmt.Sprintf("%s property %q is required in the schema only", kind, name))
+		}
+	}
+	return problems
+}
+
+func sortedPropertyNames(maps ...map[string]schema.PropertySpec) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range maps {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func typeString(t schema.TypeSpec) string {
+	switch {
+	case t.Ref != "":
+		return t.Ref
+	case t.Items != nil:
+		return "array of " + typeString(*t.Items)
+	case t.AdditionalProperties != nil:
+		return "map of " + typeString(*t.AdditionalProperties)
+	default:
+		return t.Type
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+type tag struct {
+	Key   string  `pulumi:"key" description:"Key of the tag."`
+	Value *string `pulumi:"value"`
+}
+
+type widgetInputs struct {
+	Name     string            `pulumi:"name"`
+	Size     int               `pulumi:"size,optional" deprecated:"Use width instead."`
+	Password *string           `pulumi:"password,secret"`
+	Tags     []tag             `pulumi:"tags,optional"`
+	Labels   map[string]string `pulumi:"labels,optional"`
+	Ignored  string            `pulumi:"-"`
+	internal string
+}
+
+func (args *widgetInputs) Annotate(a *resources.Annotator) {
+	a.DescribeField(&args.Name, "Name of the widget.")
+}
+
+type widgetOutputs struct {
+	Name string `pulumi:"name"`
+	ID   string `pulumi:"id"`
+}
+
+func (out *widgetOutputs) Annotate(a *resources.Annotator) {
+	a.Describe("A widget.")
+	a.DescribeField(&out.ID, "ID of the widget.")
+}
+
+func createWidget(context.Context, map[string]interface{}) (string, map[string]interface{}, error) {
+	return "widget", nil, nil
+}
+
+func TestInferSchema(t *testing.T) {
+	res := &resources.CustomResource{
+		Inputs:  widgetInputs{},
+		Outputs: widgetOutputs{},
+		Create:  createWidget,
+	}
+	if err := resources.NewRegistry("test").Register("test:index:Widget", res); err != nil {
+		t.Fatal(err)
+	}
+
+	str := schema.TypeSpec{Type: "string"}
+	expected := &schema.ResourceSpec{
+		ObjectTypeSpec: schema.ObjectTypeSpec{
+			Description: "A widget.",
+			Type:        "object",
+			Properties: map[string]schema.PropertySpec{
+				"name": {TypeSpec: str},
+				"id":   {TypeSpec: str, Description: "ID of the widget."},
+			},
+			Required: []string{"id", "name"},
+		},
+		InputProperties: map[string]schema.PropertySpec{
+			"name":     {TypeSpec: str, Description: "Name of the widget."},
+			"size":     {TypeSpec: schema.TypeSpec{Type: "integer"}, DeprecationMessage: "Use width instead."},
+			"password": {TypeSpec: str, Secret: true},
+			"tags": {TypeSpec: schema.TypeSpec{
+				Type: "array", Items: &schema.TypeSpec{Ref: "#/types/test:index:Tag"},
+			}},
+			"labels": {TypeSpec: schema.TypeSpec{Type: "object", AdditionalProperties: &str}},
+		},
+		RequiredInputs: []string{"name"},
+	}
+	if !reflect.DeepEqual(res.Schema, expected) {
+		t.Errorf("expected schema\n%+v\ngot\n%+v", expected, res.Schema)
+	}
+
+	expectedTypes := map[string]schema.ComplexTypeSpec{
+		"test:index:Tag": {ObjectTypeSpec: schema.ObjectTypeSpec{
+			Type: "object",
+			Properties: map[string]schema.PropertySpec{
+				"key":   {TypeSpec: str, Description: "Key of the tag."},
+				"value": {TypeSpec: str},
+			},
+			Required: []string{"key"},
+		}},
+	}
+	if !reflect.DeepEqual(res.Types, expectedTypes) {
+		t.Errorf("expected types\n%+v\ngot\n%+v", expectedTypes, res.Types)
+	}
+}
+
+func TestInferSchemaIsIdempotent(t *testing.T) {
+	type sizedInputs struct {
+		Name string `pulumi:"name,replaceOnChanges"`
+		Size int    `pulumi:"size" min:"1"`
+	}
+	res := &resources.CustomResource{
+		Inputs:  sizedInputs{},
+		Outputs: sizedInputs{},
+		Create:  createWidget,
+		Update: func(context.Context, map[string]interface{}) (map[string]interface{}, error) {
+			return nil, nil
+		},
+	}
+	if err := resources.NewRegistry("test").Register("test:index:Widget", res); err != nil {
+		t.Fatal(err)
+	}
+	inferred := *res
+
+	if err := resources.NewRegistry("test").Register("test:index:Widget", res); err != nil {
+		t.Fatalf("expected the resource to be registered again, got %v", err)
+	}
+	if !reflect.DeepEqual(res.Schema, inferred.Schema) || !reflect.DeepEqual(res.Constraints, inferred.Constraints) ||
+		!reflect.DeepEqual(res.ReplaceOnChanges, []string{"name"}) {
+		t.Errorf("expected registering again to keep the inferred schema, got %+v", res)
+	}
+
+	err := resources.NewRegistry("test").Register("test:gadgets:Widget", res)
+	if err == nil || !strings.Contains(err.Error(), `already inferred for "test:index:Widget"`) {
+		t.Errorf("expected registering under another token to fail, got %v", err)
+	}
+}
+
+func TestInferSchemaRejectsUntaggedFields(t *testing.T) {
+	type untagged struct {
+		Name  string `pulumi:"name"`
+		Color string
+	}
+	err := resources.NewRegistry("test").Register("test:index:Widget", &resources.CustomResource{
+		Inputs:  untagged{},
+		Outputs: widgetOutputs{},
+		Create:  createWidget,
+	})
+	if err == nil || !strings.Contains(err.Error(), "exported field Color") {
+		t.Errorf("expected the untagged field to be rejected, got %v", err)
+	}
+}
+
+func TestInferSchemaDetectsDrift(t *testing.T) {
+	str := schema.TypeSpec{Type: "string"}
+	err := resources.NewRegistry("test").Register("test:index:Widget", &resources.CustomResource{
+		Schema: &schema.ResourceSpec{
+			ObjectTypeSpec: schema.ObjectTypeSpec{
+				Properties: map[string]schema.PropertySpec{
+					"name": {TypeSpec: str},
+					"id":   {TypeSpec: schema.TypeSpec{Type: "integer"}},
+				},
+				Required: []string{"id"},
+			},
+			InputProperties: map[string]schema.PropertySpec{
+				"name":     {TypeSpec: str},
+				"password": {TypeSpec: str},
+				"color":    {TypeSpec: str},
+			},
+			RequiredInputs: []string{"color", "name"},
+		},
+		Inputs:  widgetInputs{},
+		Outputs: widgetOutputs{},
+		Create:  createWidget,
+	})
+	if err == nil {
+		t.Fatal("expected the schema to disagree with the Go types")
+	}
+	for _, problem := range []string{
+		`output property "id" has type integer in the schema but string in the Go type`,
+		`output property "name" is required in the Go type only`,
+		`input property "color" is missing from the Go type`,
+		`input property "labels" is missing from the schema`,
+		`input property "password" differs in secretness`,
+		`input property "color" is required in the schema only`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected the error to report %q, got %v", problem, err)
+		}
+	}
+}
+
+type shade string
+
+func (shade) EnumValues() []schema.EnumValueSpec {
+	return []schema.EnumValueSpec{{Value: "light"}, {Value: "dark"}}
+}
+
+type lampInputs struct {
+	Watts int    `pulumi:"watts,optional" default:"60"`
+	Label string `pulumi:"label,optional" env:"LAMP_LABEL,LABEL" default:"lamp"`
+	Shade shade  `pulumi:"shade,optional" default:"dark"`
+}
+
+func TestInferDefaults(t *testing.T) {
+	registry := resources.NewRegistry("test")
+	res := &resources.CustomResource{Inputs: lampInputs{}, Outputs: lampInputs{}, Create: createWidget}
+	if err := registry.Register("test:index:Lamp", res); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := res.Schema.InputProperties
+	if inputs["watts"].Default != 60.0 || inputs["label"].Default != "lamp" || inputs["shade"].Default != "dark" {
+		t.Errorf("expected the defaults of the tags, got %v, %v and %v",
+			inputs["watts"].Default, inputs["label"].Default, inputs["shade"].Default)
+	}
+	if info := inputs["label"].DefaultInfo; info == nil ||
+		!reflect.DeepEqual(info.Environment, []string{"LAMP_LABEL", "LABEL"}) {
+		t.Errorf("expected environment variable defaults, got %+v", info)
+	}
+
+	// The code generators accept the defaults.
+	spec, err := registry.PackageSpec(resources.Metadata{}, schema.ConfigSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schema.ImportSpec(spec, nil); err != nil {
+		t.Errorf("expected the schema to be valid, got %v", err)
+	}
+}
+
+func TestInferInvalidDefaults(t *testing.T) {
+	type badEnum struct {
+		Shade shade `pulumi:"shade,optional" default:"dim"`
+	}
+	type badObject struct {
+		Tag tag `pulumi:"tag,optional" default:"key"`
+	}
+	for _, inputs := range []interface{}{badEnum{}, badObject{}} {
+		err := resources.NewRegistry("test").Register("test:index:Lamp", &resources.CustomResource{
+			Inputs:  inputs,
+			Outputs: widgetOutputs{},
+			Create:  createWidget,
+		})
+		if err == nil {
+			t.Errorf("expected the default of %T to be rejected", inputs)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"bytes"
+	_ "embed" // for package.yaml
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed package.yaml
+var packageYAML []byte
+
+// PackageMetadata returns the metadata of the xyz package from package.yaml. The version is left to the caller.
+func PackageMetadata() (Metadata, error) {
+	meta, err := ParseMetadata(packageYAML)
+	if err != nil {
+		return Metadata{}, errors.Wrap(err, "package.yaml")
+	}
+	return meta, nil
+}
+
+// ParseMetadata reads package metadata from YAML or JSON. Unknown keys are rejected, so that typos do not go unnoticed.
+func ParseMetadata(data []byte) (Metadata, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return Metadata{}, err
+	}
+	if v == nil {
+		return Metadata{}, nil
+	}
+	var js bytes.Buffer
+	enc := json.NewEncoder(&js)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return Metadata{}, err
+	}
+
+	var meta Metadata
+	dec := json.NewDecoder(&js)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&meta); err != nil {
+		return Metadata{}, err
+	}
+	return meta, nil
+}
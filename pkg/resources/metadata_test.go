@@ -0,0 +1,76 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+func TestPackageMetadata(t *testing.T) {
+	meta, err := resources.PackageMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Version != "" {
+		t.Errorf("expected the version to be left to the build, got %q", meta.Version)
+	}
+
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := registry.PackageSpec(meta, schema.ConfigSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Description == "" || spec.Repository == "" || spec.License == "" || spec.Publisher == "" ||
+		spec.DisplayName == "" {
+		t.Errorf("expected the schema to carry the package metadata, got %+v", spec)
+	}
+	var nodejs struct {
+		PackageName string `json:"packageName"`
+	}
+	if err := json.Unmarshal(spec.Language["nodejs"], &nodejs); err != nil || nodejs.PackageName != "@pulumi/xyz" {
+		t.Errorf("expected the nodejs package name from package.yaml, got %s", spec.Language["nodejs"])
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	meta, err := resources.ParseMetadata([]byte(`
+description: Widgets.
+keywords: [a, b]
+language:
+  python:
+    requires:
+      pulumi: ">=3.0.0"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Description != "Widgets." || len(meta.Keywords) != 2 {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if python := string(meta.Language["python"]); python != `{"requires":{"pulumi":">=3.0.0"}}` {
+		t.Errorf("unexpected python settings %s", python)
+	}
+
+	if _, err := resources.ParseMetadata([]byte("homepage: https://example.com\nhomepgae: typo\n")); err == nil {
+		t.Error("expected an unknown key to fail")
+	}
+}
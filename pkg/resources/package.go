@@ -0,0 +1,158 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/contract"
+	"reflect"
+	"strings"
+)
+
+// Metadata describes a package beyond its resources. The xyz package reads it from package.yaml, see
+// PackageMetadata.
+type Metadata struct {
+	// Human-friendly name of the package. Optional.
+	DisplayName string `json:"displayName,omitempty"`
+	// Version of the package, valid semver. Optional.
+	Version string `json:"version,omitempty"`
+	// Description of the package. Optional.
+	Description string `json:"description,omitempty"`
+	// Keywords associated with the package. Optional.
+	Keywords []string `json:"keywords,omitempty"`
+	// Homepage of the package. Optional.
+	Homepage string `json:"homepage,omitempty"`
+	// Repository URL of the package source. Optional.
+	Repository string `json:"repository,omitempty"`
+	// License of the package contents. Optional.
+	License string `json:"license,omitempty"`
+	// Person or organization that authored and published the package. Optional.
+	Publisher string `json:"publisher,omitempty"`
+	// Attribution of the package contents, e.g. for the license. Optional.
+	Attribution string `json:"attribution,omitempty"`
+	// URL of the package logo. Optional.
+	LogoURL string `json:"logoUrl,omitempty"`
+	// URL to download the provider plugin binary from. Optional.
+	PluginDownloadURL string `json:"pluginDownloadURL,omitempty"`
+	// Language-specific settings of the package, keyed by language. Defaults to DefaultLanguage.
+	Language map[string]schema.RawMessage `json:"language,omitempty"`
+}
+
+// DefaultLanguage returns the language settings used for packages that do not specify their own.
+func DefaultLanguage() map[string]schema.RawMessage {
+	return map[string]schema.RawMessage{
+		"nodejs": rawMessage(map[string]interface{}{
+			"dependencies": map[string]string{
+				"@pulumi/pulumi": "^3.0.0",
+			},
+		}),
+		"python": rawMessage(map[string]interface{}{
+			"usesIOClasses": true,
+		}),
+		"csharp": rawMessage(map[string]interface{}{
+			"packageReferences": map[string]string{
+				"Pulumi":                       "3.*",
+				"System.Collections.Immutable": "1.6.0",
+			},
+		}),
+		"go": rawMessage(map[string]interface{}{}),
+	}
+}
+
+// PackageSpec builds the schema of the whole package from its metadata, the provider configuration and the
+// registered resources with their auxiliary types.
+func (r *Registry) PackageSpec(meta Metadata, config schema.ConfigSpec) (schema.PackageSpec, error) {
+	language := meta.Language
+	if language == nil {
+		language = DefaultLanguage()
+	}
+	spec := schema.PackageSpec{
+		Name:              r.pkg,
+		DisplayName:       meta.DisplayName,
+		Version:           meta.Version,
+		Description:       meta.Description,
+		Keywords:          meta.Keywords,
+		Homepage:          meta.Homepage,
+		Repository:        meta.Repository,
+		License:           meta.License,
+		Publisher:         meta.Publisher,
+		Attribution:       meta.Attribution,
+		LogoURL:           meta.LogoURL,
+		PluginDownloadURL: meta.PluginDownloadURL,
+		Config:            config,
+		Provider: schema.ResourceSpec{
+			InputProperties: config.Variables,
+		},
+		Resources: map[string]schema.ResourceSpec{},
+		Types:     map[string]schema.ComplexTypeSpec{},
+		Language:  language,
+	}
+
+	typeOwners := map[string]string{}
+	for _, tok := range r.Tokens() {
+		res := r.resources[tok]
+		resSpec := documentInputs(res)
+		for _, alias := range res.Aliases {
+			aliasType := alias
+			resSpec.Aliases = append(resSpec.Aliases, schema.AliasSpec{Type: &aliasType})
+		}
+		spec.Resources[tok] = resSpec
+
+		for typeTok, typ := range res.Types {
+			if owner, ok := typeOwners[typeTok]; ok {
+				if !reflect.DeepEqual(spec.Types[typeTok], typ) {
+					return schema.PackageSpec{}, fmt.Errorf(
+						"type %q is defined differently by resources %q and %q", typeTok, owner, tok)
+				}
+				continue
+			}
+			typeOwners[typeTok] = tok
+			spec.Types[typeTok] = typ
+		}
+	}
+
+	return spec, nil
+}
+
+// documentInputs appends the constraints of each input property to its description and notes which properties
+// force replacement of the resource when changed.
+func documentInputs(res *CustomResource) schema.ResourceSpec {
+	spec := *res.Schema
+	inputs := make(map[string]schema.PropertySpec, len(spec.InputProperties))
+	for name, prop := range spec.InputProperties {
+		if c, ok := res.Constraints[name]; ok {
+			prop.Description = strings.TrimSpace(prop.Description + " " + c.Describe())
+		}
+		if res.AutoName != nil && res.AutoName.Property == name {
+			prop.Description = strings.TrimSpace(prop.Description +
+				" If omitted, a name is generated from the resource name and a random suffix.")
+		}
+		if res.ForcesReplacement(name) {
+			prop.Description = strings.TrimSpace(prop.Description +
+				" Changing this property forces the resource to be replaced.")
+		}
+		inputs[name] = prop
+	}
+	spec.InputProperties = inputs
+	return spec
+}
+
+func rawMessage(v interface{}) schema.RawMessage {
+	bytes, err := json.Marshal(v)
+	contract.Assert(err == nil)
+	return bytes
+}
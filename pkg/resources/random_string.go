@@ -17,37 +17,28 @@ package resources
 import (
 	"context"
 	"fmt"
-	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
 	"math/rand"
 	"strconv"
 	"time"
 )
 
+type randomStringInputs struct {
+	Length int `pulumi:"length" min:"1" description:"Length of the string to generate."`
+}
+
+type randomStringOutputs struct {
+	Length int    `pulumi:"length" description:"Length of the generated string."`
+	Result string `pulumi:"result" description:"Random string that is stored in the state and is persistent across multiple runs."`
+}
+
 func newRandomStringResource() *CustomResource {
 	return &CustomResource{
-		Schema: &schema.ResourceSpec{
-			ObjectTypeSpec: schema.ObjectTypeSpec{
-				Description: "A string of random characters of a given length.",
-				Type:        "object",
-				Properties: map[string]schema.PropertySpec{
-					"length": {
-						Description: "Length of the generated string.",
-						TypeSpec: schema.TypeSpec{Type: "integer"},
-					},
-					"result": {
-						Description: "Random string that is stored in the state and is persistent across multiple runs.",
-						TypeSpec: schema.TypeSpec{Type: "string"},
-					},
-				},
-				Required: []string{"length", "result"},
-			},
-			InputProperties: map[string]schema.PropertySpec{
-				"length": {
-					Description: "Length of the string to generate.",
-					TypeSpec: schema.TypeSpec{Type: "integer"},
-				},
-			},
-			RequiredInputs: []string{"length"},
+		Description: "A string of random characters of a given length.",
+		Inputs:      randomStringInputs{},
+		Outputs:     randomStringOutputs{},
+		Samples: []map[string]interface{}{
+			{"length": 8},
+			{"length": 12},
 		},
 		Create: create,
 	}
@@ -0,0 +1,125 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/providertest"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+const randomString = "xyz:index:RandomString"
+
+func newProvider(t *testing.T) *providertest.Provider {
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	return providertest.New(t, provider.Options{Registry: registry})
+}
+
+func TestRandomStringCheck(t *testing.T) {
+	p := newProvider(t)
+
+	result, err := p.Check(randomString, "ok", nil, map[string]interface{}{"length": 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providertest.AssertNoFailures(t, result)
+
+	result, err = p.Check(randomString, "missing", nil, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providertest.AssertFailure(t, result, "length", "missing")
+
+	result, err = p.Check(randomString, "short", nil, map[string]interface{}{"length": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providertest.AssertFailure(t, result, "length", "at least 1")
+
+	for _, length := range []interface{}{"abc", true, 2.5} {
+		result, err = p.Check(randomString, "mistyped", nil, map[string]interface{}{"length": length})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Failures) != 1 {
+			t.Errorf("expected one failure for the length %v, got %v", length, result.Failures)
+		}
+	}
+	providertest.AssertFailure(t, result, "length", "length must be a whole number but got 2.5")
+}
+
+func TestRandomStringLifecycle(t *testing.T) {
+	p := newProvider(t)
+
+	res, err := p.Up(randomString, "str", map[string]interface{}{"length": 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providertest.AssertOutputs(t, res.Outputs, map[string]interface{}{"length": 8})
+	result, _ := res.Outputs["result"].(string)
+	if len(result) != 8 {
+		t.Errorf("expected a result of 8 characters, got %q", result)
+	}
+
+	diff, err := res.Update(map[string]interface{}{"length": 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Changes {
+		t.Errorf("expected no changes, got %v", diff.DetailedDiff)
+	}
+
+	exists, err := res.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("expected the resource to exist after refresh")
+	}
+	providertest.AssertOutputs(t, res.Outputs, map[string]interface{}{"length": 8, "result": result})
+
+	// RandomString has no Update operation, so every change replaces it.
+	diff, err = res.Update(map[string]interface{}{"length": 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providertest.AssertDiff(t, diff, map[string]rpc.PropertyDiff_Kind{"length": rpc.PropertyDiff_UPDATE_REPLACE})
+	if len(diff.Replaces) != 1 || diff.Replaces[0] != "length" {
+		t.Errorf("expected length to force a replacement, got %v", diff.Replaces)
+	}
+	if res.ID != "12" {
+		t.Errorf("expected the replacement to have ID 12, got %q", res.ID)
+	}
+	providertest.AssertOutputs(t, res.Outputs, map[string]interface{}{"length": 12})
+
+	if err := res.Delete(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRandomStringRecording(t *testing.T) {
+	registry := resources.NewRegistry("xyz")
+	if err := resources.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	// The recording covers a create, a no-op update, a replacement and a destroy. Results are random.
+	providertest.Replay(t, provider.Options{Registry: registry}, "testdata/random_string.jsonl", "result")
+}
@@ -0,0 +1,151 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// tokenPattern matches resource type tokens of the form `pkg:module:Type`. Modules may be nested with slashes.
+var tokenPattern = regexp.MustCompile(`^([a-z][a-z0-9-]*):([a-z][a-zA-Z0-9_/-]*):([A-Z][a-zA-Z0-9]*)$`)
+
+// Registry holds the resources of a package keyed by type token. Resources can be registered from several Go
+// packages, each exposing a function that registers its resources, or composed from separate registries with Merge.
+type Registry struct {
+	pkg       string
+	resources map[string]*CustomResource
+	aliases   map[string]string
+}
+
+// NewRegistry creates an empty registry for the resources of the given package.
+func NewRegistry(pkg string) *Registry {
+	return &Registry{
+		pkg:       pkg,
+		resources: map[string]*CustomResource{},
+		aliases:   map[string]string{},
+	}
+}
+
+// Package returns the name of the package the registry belongs to.
+func (r *Registry) Package() string {
+	return r.pkg
+}
+
+// Register adds a resource under the given token. The token must have the form `pkg:module:Type` with the package
+// of the registry, and neither it nor any alias of the resource may already be registered. The schema of the
+// resource is resolved and validated, see CustomResource.InferSchema.
+func (r *Registry) Register(token string, res *CustomResource) error {
+	if err := r.checkTokens(token, res); err != nil {
+		return err
+	}
+	if res.Create == nil {
+		return fmt.Errorf("resource %q has no Create operation defined", token)
+	}
+	if err := res.InferSchema(token); err != nil {
+		return err
+	}
+	r.add(token, res)
+	return nil
+}
+
+// MustRegister is like Register but panics if the resource cannot be registered.
+func (r *Registry) MustRegister(token string, res *CustomResource) {
+	if err := r.Register(token, res); err != nil {
+		panic(err)
+	}
+}
+
+// Merge registers all resources of another registry of the same package. The resources were validated when they were
+// registered with the other registry, so only their tokens and aliases are checked.
+func (r *Registry) Merge(other *Registry) error {
+	tokens := other.Tokens()
+	for _, tok := range tokens {
+		if err := r.checkTokens(tok, other.resources[tok]); err != nil {
+			return err
+		}
+	}
+	for _, tok := range tokens {
+		r.add(tok, other.resources[tok])
+	}
+	return nil
+}
+
+// Lookup returns the resource registered under the given token or one of its aliases, along with its current token.
+func (r *Registry) Lookup(token string) (*CustomResource, string, bool) {
+	if alias, ok := r.aliases[token]; ok {
+		token = alias
+	}
+	res, ok := r.resources[token]
+	return res, token, ok
+}
+
+// Tokens returns the sorted tokens of all registered resources.
+func (r *Registry) Tokens() []string {
+	tokens := make([]string, 0, len(r.resources))
+	for tok := range r.resources {
+		tokens = append(tokens, tok)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// checkTokens validates the token and aliases of a resource. Aliases must be distinct and differ from the token.
+func (r *Registry) checkTokens(token string, res *CustomResource) error {
+	if err := r.checkToken(token); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, alias := range res.Aliases {
+		if alias == token {
+			return fmt.Errorf("resource %q cannot be an alias of itself", token)
+		}
+		if seen[alias] {
+			return fmt.Errorf("alias %q of %q is listed more than once", alias, token)
+		}
+		seen[alias] = true
+		if err := r.checkToken(alias); err != nil {
+			return fmt.Errorf("alias of %q: %w", token, err)
+		}
+	}
+	return nil
+}
+
+// add stores a validated resource under its token and aliases.
+func (r *Registry) add(token string, res *CustomResource) {
+	r.resources[token] = res
+	for _, alias := range res.Aliases {
+		r.aliases[alias] = token
+	}
+}
+
+// checkToken validates the format of a token and that it is not taken by a resource or an alias yet.
+func (r *Registry) checkToken(token string) error {
+	match := tokenPattern.FindStringSubmatch(token)
+	if match == nil {
+		return fmt.Errorf("invalid resource token %q, expected the form pkg:module:Type", token)
+	}
+	if match[1] != r.pkg {
+		return fmt.Errorf("resource token %q does not belong to package %q", token, r.pkg)
+	}
+	if _, ok := r.resources[token]; ok {
+		return fmt.Errorf("resource %q is already registered", token)
+	}
+	if owner, ok := r.aliases[token]; ok {
+		return fmt.Errorf("resource token %q is already an alias of %q", token, owner)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-xyz/pkg/provider"
+	"github.com/pulumi/pulumi-xyz/pkg/providertest"
+	"github.com/pulumi/pulumi-xyz/pkg/resources"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	rpc "github.com/pulumi/pulumi/sdk/v3/proto/go"
+)
+
+func widget(aliases ...string) *resources.CustomResource {
+	return &resources.CustomResource{
+		Aliases: aliases,
+		Inputs:  widgetInputs{},
+		Outputs: widgetOutputs{},
+		Create:  createWidget,
+	}
+}
+
+func TestAliases(t *testing.T) {
+	registry := resources.NewRegistry("test")
+	res := widget("test:index:Widget", "test:legacy:Gizmo")
+	if err := registry.Register("test:gadgets:Widget", res); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tok := range []string{"test:gadgets:Widget", "test:index:Widget", "test:legacy:Gizmo"} {
+		found, current, ok := registry.Lookup(tok)
+		if !ok || found != res || current != "test:gadgets:Widget" {
+			t.Errorf("expected %s to resolve to test:gadgets:Widget, got %q", tok, current)
+		}
+	}
+	if _, _, ok := registry.Lookup("test:index:Gadget"); ok {
+		t.Error("expected an unknown token not to resolve")
+	}
+
+	spec, err := registry.PackageSpec(resources.Metadata{}, schema.ConfigSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := "test:index:Widget", "test:legacy:Gizmo"
+	expected := []schema.AliasSpec{{Type: &first}, {Type: &second}}
+	if aliases := spec.Resources["test:gadgets:Widget"].Aliases; !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("expected the aliases to be emitted in the schema, got %+v", aliases)
+	}
+}
+
+func TestRegisterValidatesTokens(t *testing.T) {
+	for _, tok := range []string{"test:index:Widget", "test:gadgets/v2:Widget", "test:my-module:Widget2"} {
+		if err := resources.NewRegistry("test").Register(tok, widget()); err != nil {
+			t.Errorf("expected %q to be a valid token, got %v", tok, err)
+		}
+	}
+
+	for tok, problem := range map[string]string{
+		"test:index:widget":   "invalid resource token",
+		"test:Index:Widget":   "invalid resource token",
+		"test:Widget":         "invalid resource token",
+		"test:index:Widget:1": "invalid resource token",
+		"other:index:Widget":  `does not belong to package "test"`,
+	} {
+		err := resources.NewRegistry("test").Register(tok, widget())
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q to be rejected with %q, got %v", tok, problem, err)
+		}
+	}
+}
+
+func TestRegisterRejectsCollisions(t *testing.T) {
+	registry := resources.NewRegistry("test")
+	if err := registry.Register("test:index:Widget", widget("test:legacy:Widget")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token   string
+		res     *resources.CustomResource
+		problem string
+	}{
+		{"test:index:Widget", widget(), `resource "test:index:Widget" is already registered`},
+		{"test:legacy:Widget", widget(), `already an alias of "test:index:Widget"`},
+		{"test:index:Gadget", widget("test:index:Widget"), `resource "test:index:Widget" is already registered`},
+		{"test:index:Gadget", widget("test:legacy:Widget"), `already an alias of "test:index:Widget"`},
+		{"test:index:Gadget", widget("test:index:Gadget"), "cannot be an alias of itself"},
+		{"test:index:Gadget", widget("test:legacy:Gadget", "test:legacy:Gadget"), "listed more than once"},
+		{"test:index:Gadget", widget("test:index:gadget"), "invalid resource token"},
+	}
+	for _, test := range tests {
+		err := registry.Register(test.token, test.res)
+		if err == nil || !strings.Contains(err.Error(), test.problem) {
+			t.Errorf("expected %s with aliases %v to be rejected with %q, got %v",
+				test.token, test.res.Aliases, test.problem, err)
+		}
+	}
+	if tokens := registry.Tokens(); !reflect.DeepEqual(tokens, []string{"test:index:Widget"}) {
+		t.Errorf("expected rejected resources not to be registered, got %v", tokens)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	gadgets := resources.NewRegistry("test")
+	gadget := widget("test:index:Gadget")
+	if err := gadgets.Register("test:gadgets:Gadget", gadget); err != nil {
+		t.Fatal(err)
+	}
+	// Merging does not validate the resource again.
+	gadget.Outputs = nil
+
+	registry := resources.NewRegistry("test")
+	if err := registry.Register("test:index:Widget", widget()); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Merge(gadgets); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"test:gadgets:Gadget", "test:index:Widget"}
+	if tokens := registry.Tokens(); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected the tokens %v, got %v", expected, tokens)
+	}
+	if res, tok, ok := registry.Lookup("test:index:Gadget"); !ok || res != gadget || tok != "test:gadgets:Gadget" {
+		t.Errorf("expected the aliases of merged resources to resolve, got %q", tok)
+	}
+
+	// Merges that collide register nothing.
+	if err := registry.Merge(gadgets); err == nil {
+		t.Error("expected merging the same resources twice to fail")
+	}
+	others := resources.NewRegistry("other")
+	others.MustRegister("other:index:Widget", widget())
+	if err := registry.Merge(others); err == nil {
+		t.Error("expected merging resources of another package to fail")
+	}
+	if tokens := registry.Tokens(); !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected failed merges not to register resources, got %v", tokens)
+	}
+}
+
+func TestPackageSpecTypes(t *testing.T) {
+	// Resources may share types they define identically.
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Widget", widget())
+	registry.MustRegister("test:index:Gadget", widget())
+	spec, err := registry.PackageSpec(resources.Metadata{Version: "1.2.3"}, schema.ConfigSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Types["test:index:Tag"]; !ok || len(spec.Resources) != 2 {
+		t.Errorf("expected both resources and their shared type, got %+v", spec)
+	}
+
+	// The provider serves the same schema.
+	p := providertest.New(t, provider.Options{Registry: registry, Metadata: resources.Metadata{Version: "1.2.3"}})
+	resp, err := p.Server().GetSchema(context.Background(), &rpc.GetSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var served schema.PackageSpec
+	if err := json.Unmarshal([]byte(resp.GetSchema()), &served); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := json.Marshal(served); err != nil || string(actual) != string(expected) {
+		t.Errorf("expected GetSchema to serve\n%s\ngot\n%s", expected, resp.GetSchema())
+	}
+}
+
+func TestPackageSpecRejectsConflictingTypes(t *testing.T) {
+	type tag struct {
+		Label string `pulumi:"label"`
+	}
+	type labelInputs struct {
+		Tags []tag `pulumi:"tags"`
+	}
+
+	registry := resources.NewRegistry("test")
+	registry.MustRegister("test:index:Widget", widget())
+	registry.MustRegister("test:index:Label", &resources.CustomResource{
+		Inputs: labelInputs{}, Outputs: labelInputs{}, Create: createWidget,
+	})
+	_, err := registry.PackageSpec(resources.Metadata{}, schema.ConfigSpec{})
+	expected := `type "test:index:Tag" is defined differently by resources "test:index:Label" and "test:index:Widget"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected the error %q, got %v", expected, err)
+	}
+}
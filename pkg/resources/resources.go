@@ -23,8 +23,54 @@ import (
 type CustomResource struct {
 	// Auxiliary types defined for this resource. Optional.
 	Types map[string]schema.ComplexTypeSpec
-	// Resource schema.
+	// Tokens the resource type was previously known as, e.g. before it moved to another module. Resources registered
+	// under an old token keep working and are aliased to the current token in the SDKs. Optional.
+	Aliases []string
+	// Resource schema. Optional if Inputs and Outputs are set, in which case the schema is inferred from them.
 	Schema *schema.ResourceSpec
+	// Resource description used when the schema is inferred. Optional.
+	Description string
+	// Deprecation message of the resource used when the schema is inferred. Properties are deprecated with a
+	// `deprecated:"message"` tag. Optional.
+	DeprecationMessage string
+	// A value of the Go struct type that describes the resource inputs. Fields are mapped to properties with
+	// `pulumi:"name[,optional][,secret][,replaceOnChanges]"` tags and documented with `description:"..."` tags or an Annotate method.
+	// Pointer fields and fields tagged as optional are not required. Exported fields without a tag are rejected, fields
+	// tagged `pulumi:"-"` are ignored. Default values are declared with
+	// `default:"value"` and `env:"VAR1,VAR2"` tags. Optional.
+	Inputs interface{}
+	// A value of the Go struct type that describes the resource outputs, see Inputs. Optional.
+	Outputs interface{}
+	// Constraints on input property values, keyed by property name. Constraints can also be declared with struct tags
+	// on the Inputs fields, see Constraint. Optional.
+	Constraints map[string]Constraint
+	// Names of input properties that force the resource to be replaced when changed, while changes to other
+	// properties are applied in place by Update. Inputs can also be marked with a `,replaceOnChanges` tag option.
+	// All inputs force replacement if the resource has no Update function. Optional.
+	ReplaceOnChanges []string
+	// Delete the existing resource before creating its replacement, e.g. when the resource has a unique name.
+	DeleteBeforeReplace bool
+	// Generate a default value for a name property from the logical resource name when the user omits it. Optional.
+	AutoName *AutoName
+	// Validate and normalize resource inputs, e.g. trim or canonicalize values. Runs after schema defaults and
+	// constraints have been applied and receives the old and new inputs; unknown and secret values are passed as
+	// resource.Computed and *resource.Secret. Returns the inputs to use, or nil to keep them, and any check failures.
+	// Optional.
+	Check func(ctx context.Context, olds, news map[string]interface{}) (map[string]interface{}, []CheckFailure, error)
+	// Diff the old state against the new inputs and return the names of changed input properties. Replaces the
+	// default value comparison, so that a resource can declare semantically equal values, e.g. case-insensitive
+	// names, as unchanged. Optional.
+	Diff func(ctx context.Context, olds, news map[string]interface{}) ([]string, error)
+	// Version of the shape of the resource outputs. The provider stores it in the state under StateVersionKey and
+	// upgrades state written with older versions before any resource function sees it. Defaults to 0.
+	StateVersion int
+	// Functions that upgrade state from the version given by the key to the next version. Versions without an
+	// upgrader are left unchanged. Optional.
+	StateUpgraders map[int]func(ctx context.Context, state map[string]interface{}) (map[string]interface{}, error)
+	// Example inputs used by the conformance tests in providertest. The first sample creates the resource and each
+	// following sample is applied as a change to the previous one. Optional, but resources without samples fail the
+	// conformance tests.
+	Samples []map[string]interface{}
 	// Create a new resource from a map of input values. Returns a map of resource outputs that match the schema shape.
 	Create func(context.Context, map[string]interface{}) (string, map[string]interface{}, error)
 	// Read the state of an existing resource. Constructs the resource ID based on input values. Returns a map of
@@ -34,8 +80,51 @@ type CustomResource struct {
 	Update func(context.Context, map[string]interface{}) (map[string]interface{}, error)
 	// Delete an existing resource. Constructs the resource ID based on input values.
 	Delete func(context.Context, map[string]interface{}) error
+
+	// Token the schema was inferred for, see InferSchema.
+	inferredFor string
+}
+
+// ForcesReplacement returns whether a change of the given input property replaces the resource.
+func (r *CustomResource) ForcesReplacement(name string) bool {
+	if r.Update == nil {
+		return true
+	}
+	for _, prop := range r.ReplaceOnChanges {
+		if prop == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoName configures the auto-naming of a resource: if the user omits the name property, the provider generates
+// `<logical name>-<random suffix>` and keeps it stable across updates.
+type AutoName struct {
+	// Name of the optional string input property to fill in.
+	Property string
+	// Maximum length of the generated name, including the suffix. Defaults to the MaxLength constraint of the
+	// property, if any.
+	MaxLength int
+	// Characters of the random suffix, to which the logical name is also reduced. Defaults to lowercase letters and
+	// digits.
+	Charset string
+	// Length of the random suffix. Defaults to 7.
+	SuffixLength int
+}
+
+// StateVersionKey is the reserved output property under which the provider stores the state version of a resource.
+const StateVersionKey = "__stateVersion"
+
+// CheckFailure describes why an input property of a resource is invalid.
+type CheckFailure struct {
+	// Name of the invalid property.
+	Property string
+	// Reason the property is invalid.
+	Reason string
 }
 
-var Resources = map[string]*CustomResource{
-	"xyz:index:RandomString": newRandomStringResource(),
+// Register adds the resources of this package to the registry.
+func Register(r *Registry) error {
+	return r.Register("xyz:index:RandomString", newRandomStringResource())
 }
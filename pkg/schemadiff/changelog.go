@@ -0,0 +1,137 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemadiff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// changelogSections are the headings of a changelog, in order.
+var changelogSections = []string{
+	"New resources",
+	"New functions",
+	"New properties",
+	"Removed properties",
+	"Deprecations",
+	"Other changes",
+}
+
+// Changelog renders the changes as a markdown changelog section with the given title, e.g. the version of the
+// release. Changes are grouped by module, then by section. Breaking changes are marked as such.
+func Changelog(title string, changes []Change) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "## %s\n", title)
+	if len(changes) == 0 {
+		buf.WriteString("\nNo changes to the schema.\n")
+		return buf.Bytes()
+	}
+
+	modules := map[string]map[string][]string{}
+	for _, c := range changes {
+		mod := module(c.Token)
+		if modules[mod] == nil {
+			modules[mod] = map[string][]string{}
+		}
+		section := changelogSection(c)
+		modules[mod][section] = append(modules[mod][section], changelogEntry(c))
+	}
+
+	names := make([]string, 0, len(modules))
+	for mod := range modules {
+		names = append(names, mod)
+	}
+	sort.Strings(names)
+	for _, mod := range names {
+		fmt.Fprintf(&buf, "\n### Module `%s`\n", mod)
+		for _, section := range changelogSections {
+			entries := modules[mod][section]
+			if len(entries) == 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "\n#### %s\n\n", section)
+			for _, entry := range entries {
+				fmt.Fprintf(&buf, "- %s\n", entry)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// module returns the module of a token of the form package:module:name. Modules may be nested with slashes, as in
+// index/widget, of which the first part is used. The provider resource has the module providers.
+func module(tok string) string {
+	parts := strings.Split(tok, ":")
+	if len(parts) != 3 {
+		return tok
+	}
+	return strings.SplitN(parts[1], "/", 2)[0]
+}
+
+func changelogSection(c Change) string {
+	switch {
+	case c.Kind == Deprecated:
+		return "Deprecations"
+	case c.Member == "" && c.Kind == Added && c.Element == Resource:
+		return "New resources"
+	case c.Member == "" && c.Kind == Added && c.Element == Function:
+		return "New functions"
+	case c.Member != "" && c.Member != EnumValue && c.Kind == Added:
+		return "New properties"
+	case c.Member != "" && c.Member != EnumValue && c.Kind == Removed:
+		return "Removed properties"
+	default:
+		return "Other changes"
+	}
+}
+
+func changelogEntry(c Change) string {
+	var entry string
+	switch {
+	case c.Member != "":
+		entry = fmt.Sprintf("`%s` %s `%s`", c.Token, c.Member, c.Name)
+	case c.Kind == Added && (c.Element == Resource || c.Element == Function):
+		entry = fmt.Sprintf("`%s`", c.Token)
+	default:
+		elem := string(c.Element)
+		entry = fmt.Sprintf("%s%s `%s`", strings.ToUpper(elem[:1]), elem[1:], c.Token)
+	}
+
+	switch c.Kind {
+	case TypeChanged:
+		entry += fmt.Sprintf(" changed type from `%s` to `%s`", c.Old, c.New)
+	case MadeRequired:
+		entry += " is now required"
+	case MadeOptional:
+		entry += " is now optional"
+	case Deprecated:
+		entry += ": " + c.Message
+	case Added:
+		if c.Member == EnumValue || (c.Member == "" && c.Element == Type) {
+			entry += " was added"
+		}
+	case Removed:
+		if c.Member == EnumValue || c.Member == "" {
+			entry += " was removed"
+		}
+	}
+
+	if c.Breaking {
+		entry = "**Breaking:** " + entry
+	}
+	return entry
+}
@@ -0,0 +1,74 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemadiff
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+func TestChangelog(t *testing.T) {
+	spec := oldSchema()
+	widget := spec.Resources["xyz:index:Widget"]
+	widget.DeprecationMessage = "Use Gizmo instead."
+	widget.InputProperties = map[string]schema.PropertySpec{
+		"size":   {TypeSpec: integerType},
+		"color":  {TypeSpec: stringType, DeprecationMessage: "Colors are always blue."},
+		"label":  {TypeSpec: stringType},
+		"weight": {TypeSpec: integerType},
+	}
+	delete(widget.Properties, "label")
+	widget.Required = []string{"size"}
+	spec.Resources["xyz:index:Widget"] = widget
+	delete(spec.Resources, "xyz:index:Gadget")
+	spec.Resources["xyz:tools:Hammer"] = schema.ResourceSpec{}
+	spec.Functions["xyz:tools:getHammer"] = schema.FunctionSpec{}
+	spec.Types["xyz:index:Shape"] = schema.ComplexTypeSpec{
+		ObjectTypeSpec: schema.ObjectTypeSpec{Type: "string"},
+		Enum: []schema.EnumValueSpec{
+			{Value: "round", DeprecationMessage: "Use oval."}, {Value: "square"}, {Value: "oval"},
+		},
+	}
+
+	expected := "## v1.2.0\n" +
+		"\n### Module `index`\n" +
+		"\n#### New properties\n\n" +
+		"- `xyz:index:Widget` input `weight`\n" +
+		"\n#### Removed properties\n\n" +
+		"- **Breaking:** `xyz:index:Widget` output `label`\n" +
+		"\n#### Deprecations\n\n" +
+		"- Resource `xyz:index:Widget`: Use Gizmo instead.\n" +
+		"- `xyz:index:Widget` input `color`: Colors are always blue.\n" +
+		"- `xyz:index:Shape` enum value `round`: Use oval.\n" +
+		"\n#### Other changes\n\n" +
+		"- **Breaking:** Resource `xyz:index:Gadget` was removed\n" +
+		"- `xyz:index:Shape` enum value `oval` was added\n" +
+		"\n### Module `tools`\n" +
+		"\n#### New resources\n\n" +
+		"- `xyz:tools:Hammer`\n" +
+		"\n#### New functions\n\n" +
+		"- `xyz:tools:getHammer`\n"
+	if actual := string(Changelog("v1.2.0", Compare(oldSchema(), spec))); actual != expected {
+		t.Errorf("expected changelog\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestEmptyChangelog(t *testing.T) {
+	expected := "## Unreleased\n\nNo changes to the schema.\n"
+	if actual := string(Changelog("Unreleased", nil)); actual != expected {
+		t.Errorf("expected changelog %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schemadiff compares two versions of a package schema and classifies the changes into breaking and additive
+// ones, so that releases do not break existing programs by accident.
+package schemadiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+// Element is the kind of schema element a change applies to.
+type Element string
+
+const (
+	Resource Element = "resource"
+	Function Element = "function"
+	Type     Element = "type"
+)
+
+// Member is the kind of part of an element a change applies to.
+type Member string
+
+const (
+	Input     Member = "input"
+	Output    Member = "output"
+	Argument  Member = "argument"
+	Result    Member = "result"
+	Property  Member = "property"
+	EnumValue Member = "enum value"
+)
+
+// Kind is what happened to an element or member.
+type Kind string
+
+const (
+	Added        Kind = "added"
+	Removed      Kind = "removed"
+	TypeChanged  Kind = "type changed"
+	MadeRequired Kind = "made required"
+	MadeOptional Kind = "made optional"
+	Deprecated   Kind = "deprecated"
+)
+
+// Change is a difference between two versions of a package schema.
+type Change struct {
+	Kind Kind
+	// Breaking changes may break programs or SDK users that worked with the old version.
+	Breaking bool
+	Element  Element
+	// Token of the resource, function or type.
+	Token string
+	// Member and its name, if the change applies to a part of the element rather than the element itself.
+	Member Member
+	Name   string
+	// Old and New describe the types of a TypeChanged change.
+	Old, New string
+	// Message is the deprecation message of a Deprecated change.
+	Message string
+}
+
+func (c Change) String() string {
+	subject := fmt.Sprintf("%s %q", c.Element, c.Token)
+	if c.Member != "" {
+		subject = fmt.Sprintf("%s %s %q", subject, c.Member, c.Name)
+	}
+	switch c.Kind {
+	case TypeChanged:
+		return fmt.Sprintf("%s changed type from %s to %s", subject, c.Old, c.New)
+	case MadeRequired:
+		return subject + " is now required"
+	case MadeOptional:
+		return subject + " is now optional"
+	case Deprecated:
+		return fmt.Sprintf("%s was deprecated: %s", subject, c.Message)
+	default:
+		return fmt.Sprintf("%s was %s", subject, c.Kind)
+	}
+}
+
+// Compare returns the changes from the old to the new package schema, ordered by element and token. Descriptions and
+// language settings are ignored.
+func Compare(old, new schema.PackageSpec) []Change {
+	d := &differ{}
+
+	d.resource("pulumi:providers:"+new.Name, old.Provider, new.Provider)
+	for _, tok := range sortedKeys(old.Resources, new.Resources) {
+		o, inOld := old.Resources[tok]
+		n, inNew := new.Resources[tok]
+		if d.element(Resource, tok, inOld, inNew) {
+			d.resource(tok, o, n)
+		}
+	}
+	for _, tok := range sortedKeys(old.Functions, new.Functions) {
+		o, inOld := old.Functions[tok]
+		n, inNew := new.Functions[tok]
+		if d.element(Function, tok, inOld, inNew) {
+			d.deprecated(Change{Element: Function, Token: tok}, o.DeprecationMessage, n.DeprecationMessage)
+			d.objects(Function, tok, Argument, objectOrEmpty(o.Inputs), objectOrEmpty(n.Inputs))
+			d.objects(Function, tok, Result, objectOrEmpty(o.Outputs), objectOrEmpty(n.Outputs))
+		}
+	}
+	for _, tok := range sortedKeys(old.Types, new.Types) {
+		o, inOld := old.Types[tok]
+		n, inNew := new.Types[tok]
+		if d.element(Type, tok, inOld, inNew) {
+			d.complexType(tok, o, n)
+		}
+	}
+	return d.changes
+}
+
+// Breaking returns the breaking changes.
+func Breaking(changes []Change) []Change {
+	var breaking []Change
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+// element records an added or removed element and reports whether the element exists in both versions.
+func (d *differ) element(elem Element, tok string, inOld, inNew bool) bool {
+	switch {
+	case !inNew:
+		d.add(Change{Kind: Removed, Breaking: true, Element: elem, Token: tok})
+	case !inOld:
+		d.add(Change{Kind: Added, Element: elem, Token: tok})
+	}
+	return inOld && inNew
+}
+
+// deprecated records a deprecation if an element or member was not deprecated before.
+func (d *differ) deprecated(c Change, oldMessage, newMessage string) {
+	if oldMessage == "" && newMessage != "" {
+		c.Kind, c.Message = Deprecated, newMessage
+		d.add(c)
+	}
+}
+
+func (d *differ) resource(tok string, old, new schema.ResourceSpec) {
+	d.deprecated(Change{Element: Resource, Token: tok}, old.DeprecationMessage, new.DeprecationMessage)
+	d.objects(Resource, tok, Input,
+		schema.ObjectTypeSpec{Properties: old.InputProperties, Required: old.RequiredInputs},
+		schema.ObjectTypeSpec{Properties: new.InputProperties, Required: new.RequiredInputs})
+	d.objects(Resource, tok, Output, old.ObjectTypeSpec, new.ObjectTypeSpec)
+}
+
+func (d *differ) complexType(tok string, old, new schema.ComplexTypeSpec) {
+	oldKind, newKind := typeKind(old), typeKind(new)
+	if oldKind != newKind {
+		d.add(Change{Kind: TypeChanged, Breaking: true, Element: Type, Token: tok, Old: oldKind, New: newKind})
+		return
+	}
+	if len(new.Enum) == 0 {
+		d.objects(Type, tok, Property, old.ObjectTypeSpec, new.ObjectTypeSpec)
+		return
+	}
+
+	values := map[string]schema.EnumValueSpec{}
+	for _, v := range new.Enum {
+		values[fmt.Sprint(v.Value)] = v
+	}
+	oldValues := map[string]bool{}
+	for _, v := range old.Enum {
+		name := fmt.Sprint(v.Value)
+		oldValues[name] = true
+		change := Change{Element: Type, Token: tok, Member: EnumValue, Name: name}
+		if n, ok := values[name]; ok {
+			d.deprecated(change, v.DeprecationMessage, n.DeprecationMessage)
+		} else {
+			change.Kind, change.Breaking = Removed, true
+			d.add(change)
+		}
+	}
+	for _, v := range new.Enum {
+		if !oldValues[fmt.Sprint(v.Value)] {
+			d.add(Change{Kind: Added, Element: Type, Token: tok, Member: EnumValue, Name: fmt.Sprint(v.Value)})
+		}
+	}
+}
+
+// objects compares the properties of an element. Which changes break users depends on the direction the values flow
+// in: users provide inputs and arguments, so new required ones break them, and they read outputs and results, so
+// removing a guarantee that a value is present breaks them. Properties of object types may flow both ways.
+func (d *differ) objects(elem Element, tok string, member Member, old, new schema.ObjectTypeSpec) {
+	provided := member == Input || member == Argument || member == Property
+	consumed := member == Output || member == Result || member == Property
+
+	oldRequired, newRequired := set(old.Required), set(new.Required)
+	for _, name := range sortedKeys(old.Properties, new.Properties) {
+		o, inOld := old.Properties[name]
+		n, inNew := new.Properties[name]
+		change := Change{Element: elem, Token: tok, Member: member, Name: name}
+		switch {
+		case !inNew:
+			change.Kind, change.Breaking = Removed, true
+		case !inOld:
+			change.Kind, change.Breaking = Added, provided && newRequired[name]
+		case !sameType(o.TypeSpec, n.TypeSpec):
+			change.Kind, change.Breaking = TypeChanged, true
+			change.Old, change.New = typeString(o.TypeSpec), typeString(n.TypeSpec)
+		case !oldRequired[name] && newRequired[name]:
+			change.Kind, change.Breaking = MadeRequired, provided
+		case oldRequired[name] && !newRequired[name]:
+			change.Kind, change.Breaking = MadeOptional, consumed
+		}
+		if change.Kind != "" {
+			d.add(change)
+		}
+		if inOld && inNew {
+			d.deprecated(Change{Element: elem, Token: tok, Member: member, Name: name}, o.DeprecationMessage,
+				n.DeprecationMessage)
+		}
+	}
+}
+
+// sameType reports whether two types are the same. They are compared in their JSON form, which is what users of the
+// schema see, so that e.g. nil and empty unions are the same.
+func sameType(a, b schema.TypeSpec) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+func objectOrEmpty(spec *schema.ObjectTypeSpec) schema.ObjectTypeSpec {
+	if spec == nil {
+		return schema.ObjectTypeSpec{}
+	}
+	return *spec
+}
+
+// typeKind describes what kind of type a complex type is.
+func typeKind(spec schema.ComplexTypeSpec) string {
+	if len(spec.Enum) > 0 {
+		return "enum of " + spec.Type
+	}
+	return spec.Type
+}
+
+func typeString(t schema.TypeSpec) string {
+	switch {
+	case t.Ref != "":
+		return t.Ref
+	case t.Items != nil:
+		return "array of " + typeString(*t.Items)
+	case t.AdditionalProperties != nil:
+		return "map of " + typeString(*t.AdditionalProperties)
+	case len(t.OneOf) > 0:
+		types := make([]string, len(t.OneOf))
+		for i, o := range t.OneOf {
+			types[i] = typeString(o)
+		}
+		return "one of " + strings.Join(types, ", ")
+	default:
+		return t.Type
+	}
+}
+
+func set(names []string) map[string]bool {
+	s := make(map[string]bool, len(names))
+	for _, name := range names {
+		s[name] = true
+	}
+	return s
+}
+
+// sortedKeys returns the keys of both maps, which must have the same type, in order.
+func sortedKeys(a, b interface{}) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []reflect.Value{reflect.ValueOf(a), reflect.ValueOf(b)} {
+		for _, k := range m.MapKeys() {
+			if !seen[k.String()] {
+				seen[k.String()] = true
+				keys = append(keys, k.String())
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
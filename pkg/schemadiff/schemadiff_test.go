@@ -0,0 +1,172 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemadiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+)
+
+var (
+	stringType  = schema.TypeSpec{Type: "string"}
+	integerType = schema.TypeSpec{Type: "integer"}
+)
+
+func oldSchema() schema.PackageSpec {
+	return schema.PackageSpec{
+		Name: "xyz",
+		Resources: map[string]schema.ResourceSpec{
+			"xyz:index:Widget": {
+				ObjectTypeSpec: schema.ObjectTypeSpec{
+					Properties: map[string]schema.PropertySpec{
+						"size":  {TypeSpec: integerType},
+						"color": {TypeSpec: stringType},
+						"label": {TypeSpec: stringType},
+					},
+					Required: []string{"size", "label"},
+				},
+				InputProperties: map[string]schema.PropertySpec{
+					"size":  {TypeSpec: integerType},
+					"color": {TypeSpec: stringType},
+					"label": {TypeSpec: stringType},
+				},
+				RequiredInputs: []string{"size", "label"},
+			},
+			"xyz:index:Gadget": {},
+		},
+		Functions: map[string]schema.FunctionSpec{
+			"xyz:index:getWidget": {Inputs: &schema.ObjectTypeSpec{
+				Properties: map[string]schema.PropertySpec{"id": {TypeSpec: stringType}},
+				Required:   []string{"id"},
+			}},
+		},
+		Types: map[string]schema.ComplexTypeSpec{
+			"xyz:index:Shape": {
+				ObjectTypeSpec: schema.ObjectTypeSpec{Type: "string"},
+				Enum:           []schema.EnumValueSpec{{Value: "round"}, {Value: "square"}},
+			},
+		},
+	}
+}
+
+func TestNoChanges(t *testing.T) {
+	if changes := Compare(oldSchema(), oldSchema()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	spec := oldSchema()
+	widget := spec.Resources["xyz:index:Widget"]
+	widget.InputProperties = map[string]schema.PropertySpec{
+		"size":   {TypeSpec: schema.TypeSpec{Type: "number"}},
+		"color":  {TypeSpec: stringType},
+		"label":  {TypeSpec: stringType},
+		"weight": {TypeSpec: integerType},
+		"owner":  {TypeSpec: stringType},
+	}
+	widget.RequiredInputs = []string{"size", "color", "owner"}
+	widget.Properties = map[string]schema.PropertySpec{
+		"size":  {TypeSpec: integerType},
+		"label": {TypeSpec: stringType},
+		"extra": {TypeSpec: stringType},
+	}
+	widget.Required = []string{"size"}
+	spec.Resources["xyz:index:Widget"] = widget
+	delete(spec.Resources, "xyz:index:Gadget")
+	spec.Resources["xyz:index:Gizmo"] = schema.ResourceSpec{}
+	spec.Functions["xyz:index:getGizmo"] = schema.FunctionSpec{}
+	spec.Types["xyz:index:Shape"] = schema.ComplexTypeSpec{
+		ObjectTypeSpec: schema.ObjectTypeSpec{Type: "string"},
+		Enum:           []schema.EnumValueSpec{{Value: "round"}, {Value: "oval"}},
+	}
+
+	expected := []string{
+		`breaking: resource "xyz:index:Gadget" was removed`,
+		`additive: resource "xyz:index:Gizmo" was added`,
+		`breaking: resource "xyz:index:Widget" input "color" is now required`,
+		`additive: resource "xyz:index:Widget" input "label" is now optional`,
+		`breaking: resource "xyz:index:Widget" input "owner" was added`,
+		`breaking: resource "xyz:index:Widget" input "size" changed type from integer to number`,
+		`additive: resource "xyz:index:Widget" input "weight" was added`,
+		`breaking: resource "xyz:index:Widget" output "color" was removed`,
+		`additive: resource "xyz:index:Widget" output "extra" was added`,
+		`breaking: resource "xyz:index:Widget" output "label" is now optional`,
+		`additive: function "xyz:index:getGizmo" was added`,
+		`breaking: type "xyz:index:Shape" enum value "square" was removed`,
+		`additive: type "xyz:index:Shape" enum value "oval" was added`,
+	}
+	var actual []string
+	for _, c := range Compare(oldSchema(), spec) {
+		prefix := "additive: "
+		if c.Breaking {
+			prefix = "breaking: "
+		}
+		actual = append(actual, prefix+c.String())
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected changes\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	spec := oldSchema()
+	spec.Functions["xyz:index:getWidget"] = schema.FunctionSpec{Inputs: &schema.ObjectTypeSpec{
+		Properties: map[string]schema.PropertySpec{
+			"id":   {TypeSpec: stringType},
+			"name": {TypeSpec: stringType},
+		},
+		Required: []string{"id", "name"},
+	}}
+	changes := Breaking(Compare(oldSchema(), spec))
+	if len(changes) != 1 || changes[0].String() != `function "xyz:index:getWidget" argument "name" was added` {
+		t.Errorf("expected a new required argument to break, got %v", changes)
+	}
+}
+
+func TestEquivalentTypes(t *testing.T) {
+	spec := oldSchema()
+	widget := spec.Resources["xyz:index:Widget"]
+	widget.InputProperties["size"] = schema.PropertySpec{
+		TypeSpec: schema.TypeSpec{Type: "integer", OneOf: []schema.TypeSpec{}},
+	}
+	if changes := Compare(oldSchema(), spec); len(changes) != 0 {
+		t.Errorf("expected an empty union to be the same as none, got %v", changes)
+	}
+}
+
+func TestNestedTypes(t *testing.T) {
+	tag := func(required ...string) schema.ComplexTypeSpec {
+		return schema.ComplexTypeSpec{ObjectTypeSpec: schema.ObjectTypeSpec{
+			Type: "object",
+			Properties: map[string]schema.PropertySpec{
+				"key":   {TypeSpec: stringType},
+				"value": {TypeSpec: stringType},
+			},
+			Required: required,
+		}}
+	}
+	old := oldSchema()
+	old.Types["xyz:index:Tag"] = tag("key")
+	spec := oldSchema()
+	spec.Types["xyz:index:Tag"] = tag("key", "value")
+
+	changes := Breaking(Compare(old, spec))
+	if len(changes) != 1 || changes[0].String() != `type "xyz:index:Tag" property "value" is now required` {
+		t.Errorf("expected a newly required property of a nested type to break, got %v", changes)
+	}
+}
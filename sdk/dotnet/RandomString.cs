@@ -13,7 +13,7 @@ namespace Pulumi.Xyz
     /// A string of random characters of a given length.
     /// </summary>
     [XyzResourceType("xyz:index:RandomString")]
-    public partial class RandomString : Pulumi.CustomResource
+    public partial class RandomString : global::Pulumi.CustomResource
     {
         /// <summary>
         /// Length of the generated string.
@@ -70,10 +70,10 @@ namespace Pulumi.Xyz
         }
     }
 
-    public sealed class RandomStringArgs : Pulumi.ResourceArgs
+    public sealed class RandomStringArgs : global::Pulumi.ResourceArgs
     {
         /// <summary>
-        /// Length of the string to generate.
+        /// Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
         /// </summary>
         [Input("length", required: true)]
         public Input<int> Length { get; set; } = null!;
@@ -81,5 +81,6 @@ namespace Pulumi.Xyz
         public RandomStringArgs()
         {
         }
+        public static new RandomStringArgs Empty => new RandomStringArgs();
     }
 }
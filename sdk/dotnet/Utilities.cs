@@ -1,11 +1,6 @@
 // *** WARNING: this file was generated by the Pulumi SDK Generator. ***
 // *** Do not edit by hand unless you're certain you know what you are doing! ***
 
-using System;
-using System.IO;
-using System.Reflection;
-using Pulumi;
-
 namespace Pulumi.Xyz
 {
     static class Utilities
@@ -14,7 +9,7 @@ namespace Pulumi.Xyz
         {
             foreach (var n in names)
             {
-                var value = Environment.GetEnvironmentVariable(n);
+                var value = global::System.Environment.GetEnvironmentVariable(n);
                 if (value != null)
                 {
                     return value;
@@ -30,11 +25,11 @@ namespace Pulumi.Xyz
             var s = GetEnv(names);
             if (s != null)
             {
-                if (Array.IndexOf(trueValues, s) != -1)
+                if (global::System.Array.IndexOf(trueValues, s) != -1)
                 {
                     return true;
                 }
-                if (Array.IndexOf(falseValues, s) != -1)
+                if (global::System.Array.IndexOf(falseValues, s) != -1)
                 {
                     return false;
                 }
@@ -46,18 +41,19 @@ namespace Pulumi.Xyz
 
         public static double? GetEnvDouble(params string[] names) => double.TryParse(GetEnv(names), out double v) ? (double?)v : null;
 
-        public static InvokeOptions WithVersion(this InvokeOptions? options)
+        [global::System.Obsolete("Please use WithDefaults instead")]
+        public static global::Pulumi.InvokeOptions WithVersion(this global::Pulumi.InvokeOptions? options)
         {
-            if (options?.Version != null)
-            {
-                return options;
-            }
-            return new InvokeOptions
-            {
-                Parent = options?.Parent,
-                Provider = options?.Provider,
-                Version = Version,
-            };
+            var dst = options ?? new global::Pulumi.InvokeOptions{};
+            dst.Version = options?.Version ?? Version;
+            return dst;
+        }
+
+        public static global::Pulumi.InvokeOptions WithDefaults(this global::Pulumi.InvokeOptions? src)
+        {
+            var dst = src ?? new global::Pulumi.InvokeOptions{};
+            dst.Version = src?.Version ?? Version;
+            return dst;
         }
 
         private readonly static string version;
@@ -65,9 +61,9 @@ namespace Pulumi.Xyz
 
         static Utilities()
         {
-            var assembly = typeof(Utilities).GetTypeInfo().Assembly;
+            var assembly = global::System.Reflection.IntrospectionExtensions.GetTypeInfo(typeof(Utilities)).Assembly;
             using var stream = assembly.GetManifestResourceStream("Pulumi.Xyz.version.txt");
-            using var reader = new StreamReader(stream ?? throw new NotSupportedException("Missing embedded version.txt file"));
+            using var reader = new global::System.IO.StreamReader(stream ?? throw new global::System.NotSupportedException("Missing embedded version.txt file"));
             version = reader.ReadToEnd().Trim();
             var parts = version.Split("\n");
             if (parts.Length == 2)
@@ -78,7 +74,7 @@ namespace Pulumi.Xyz
         }
     }
 
-    internal sealed class XyzResourceTypeAttribute : Pulumi.ResourceTypeAttribute
+    internal sealed class XyzResourceTypeAttribute : global::Pulumi.ResourceTypeAttribute
     {
         public XyzResourceTypeAttribute(string type) : base(type, Utilities.Version)
         {
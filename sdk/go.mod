@@ -4,7 +4,7 @@ go 1.16
 
 require (
 	github.com/blang/semver v3.5.1+incompatible
+	github.com/fatih/color v1.10.0 // indirect
 	github.com/pkg/errors v0.9.1
-	github.com/pulumi/go-yaml v1.8.10-0.20210311214047-95da20da6e94 // indirect
-	github.com/pulumi/pulumi/sdk/v3 v3.0.0
+	github.com/pulumi/pulumi/sdk/v3 v3.38.0
 )
@@ -1,3 +1,3 @@
-// Package xyz exports types, functions, subpackages for provisioning xyz resources.
-//
+// A Pulumi package for creating and managing xyz resources.
+
 package xyz
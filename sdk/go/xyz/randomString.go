@@ -1,5 +1,5 @@
-// *** WARNING: this file was generated by the Pulumi SDK Generator. ***
-// *** Do not edit by hand unless you're certain you know what you are doing! ***
+// Code generated by the Pulumi SDK Generator DO NOT EDIT.
+// *** WARNING: Do not edit by hand unless you're certain you know what you are doing! ***
 
 package xyz
 
@@ -53,17 +53,9 @@ func GetRandomString(ctx *pulumi.Context,
 
 // Input properties used for looking up and filtering RandomString resources.
 type randomStringState struct {
-	// Length of the generated string.
-	Length *int `pulumi:"length"`
-	// Random string that is stored in the state and is persistent across multiple runs.
-	Result *string `pulumi:"result"`
 }
 
 type RandomStringState struct {
-	// Length of the generated string.
-	Length pulumi.IntPtrInput
-	// Random string that is stored in the state and is persistent across multiple runs.
-	Result pulumi.StringPtrInput
 }
 
 func (RandomStringState) ElementType() reflect.Type {
@@ -71,13 +63,13 @@ func (RandomStringState) ElementType() reflect.Type {
 }
 
 type randomStringArgs struct {
-	// Length of the string to generate.
+	// Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
 	Length int `pulumi:"length"`
 }
 
 // The set of arguments for constructing a RandomString resource.
 type RandomStringArgs struct {
-	// Length of the string to generate.
+	// Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
 	Length pulumi.IntInput
 }
 
@@ -93,7 +85,7 @@ type RandomStringInput interface {
 }
 
 func (*RandomString) ElementType() reflect.Type {
-	return reflect.TypeOf((*RandomString)(nil))
+	return reflect.TypeOf((**RandomString)(nil)).Elem()
 }
 
 func (i *RandomString) ToRandomStringOutput() RandomStringOutput {
@@ -104,12 +96,10 @@ func (i *RandomString) ToRandomStringOutputWithContext(ctx context.Context) Rand
 	return pulumi.ToOutputWithContext(ctx, i).(RandomStringOutput)
 }
 
-type RandomStringOutput struct {
-	*pulumi.OutputState
-}
+type RandomStringOutput struct{ *pulumi.OutputState }
 
 func (RandomStringOutput) ElementType() reflect.Type {
-	return reflect.TypeOf((*RandomString)(nil))
+	return reflect.TypeOf((**RandomString)(nil)).Elem()
 }
 
 func (o RandomStringOutput) ToRandomStringOutput() RandomStringOutput {
@@ -120,6 +110,17 @@ func (o RandomStringOutput) ToRandomStringOutputWithContext(ctx context.Context)
 	return o
 }
 
+// Length of the generated string.
+func (o RandomStringOutput) Length() pulumi.IntOutput {
+	return o.ApplyT(func(v *RandomString) pulumi.IntOutput { return v.Length }).(pulumi.IntOutput)
+}
+
+// Random string that is stored in the state and is persistent across multiple runs.
+func (o RandomStringOutput) Result() pulumi.StringOutput {
+	return o.ApplyT(func(v *RandomString) pulumi.StringOutput { return v.Result }).(pulumi.StringOutput)
+}
+
 func init() {
+	pulumi.RegisterInputType(reflect.TypeOf((*RandomStringInput)(nil)).Elem(), &RandomString{})
 	pulumi.RegisterOutputType(RandomStringOutput{})
 }
@@ -51,22 +51,20 @@ export class RandomString extends pulumi.CustomResource {
      * @param opts A bag of options that control this resource's behavior.
      */
     constructor(name: string, args: RandomStringArgs, opts?: pulumi.CustomResourceOptions) {
-        let inputs: pulumi.Inputs = {};
+        let resourceInputs: pulumi.Inputs = {};
         opts = opts || {};
         if (!opts.id) {
             if ((!args || args.length === undefined) && !opts.urn) {
                 throw new Error("Missing required property 'length'");
             }
-            inputs["length"] = args ? args.length : undefined;
-            inputs["result"] = undefined /*out*/;
+            resourceInputs["length"] = args ? args.length : undefined;
+            resourceInputs["result"] = undefined /*out*/;
         } else {
-            inputs["length"] = undefined /*out*/;
-            inputs["result"] = undefined /*out*/;
+            resourceInputs["length"] = undefined /*out*/;
+            resourceInputs["result"] = undefined /*out*/;
         }
-        if (!opts.version) {
-            opts = pulumi.mergeOptions(opts, { version: utilities.getVersion()});
-        }
-        super(RandomString.__pulumiType, name, inputs, opts);
+        opts = pulumi.mergeOptions(utilities.resourceOptsDefaults(), opts);
+        super(RandomString.__pulumiType, name, resourceInputs, opts);
     }
 }
 
@@ -75,7 +73,7 @@ export class RandomString extends pulumi.CustomResource {
  */
 export interface RandomStringArgs {
     /**
-     * Length of the string to generate.
+     * Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
      */
-    readonly length: pulumi.Input<number>;
+    length: pulumi.Input<number>;
 }
@@ -2,44 +2,32 @@
 # *** WARNING: this file was generated by the Pulumi SDK Generator. ***
 # *** Do not edit by hand unless you're certain you know what you are doing! ***
 
+from . import _utilities
+import typing
 # Export this package's modules as members:
 from .provider import *
 from .random_string import *
-
-def _register_module():
-    import pulumi
-    from . import _utilities
-
-
-    class Module(pulumi.runtime.ResourceModule):
-        _version = _utilities.get_semver_version()
-
-        def version(self):
-            return Module._version
-
-        def construct(self, name: str, typ: str, urn: str) -> pulumi.Resource:
-            if typ == "xyz:index:RandomString":
-                return RandomString(name, pulumi.ResourceOptions(urn=urn))
-            else:
-                raise Exception(f"unknown resource type {typ}")
-
-
-    _module_instance = Module()
-    pulumi.runtime.register_resource_module("xyz", "index", _module_instance)
-
-
-    class Package(pulumi.runtime.ResourcePackage):
-        _version = _utilities.get_semver_version()
-
-        def version(self):
-            return Package._version
-
-        def construct_provider(self, name: str, typ: str, urn: str) -> pulumi.ProviderResource:
-            if typ != "pulumi:providers:xyz":
-                raise Exception(f"unknown provider type {typ}")
-            return Provider(name, pulumi.ResourceOptions(urn=urn))
-
-
-    pulumi.runtime.register_resource_package("xyz", Package())
-
-_register_module()
+_utilities.register(
+    resource_modules="""
+[
+ {
+  "pkg": "xyz",
+  "mod": "index",
+  "fqn": "pulumi_xyz",
+  "classes": {
+   "xyz:index:RandomString": "RandomString"
+  }
+ }
+]
+""",
+    resource_packages="""
+[
+ {
+  "pkg": "xyz",
+  "token": "pulumi:providers:xyz",
+  "fqn": "pulumi_xyz",
+  "class": "Provider"
+ }
+]
+"""
+)
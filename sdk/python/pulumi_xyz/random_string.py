@@ -2,6 +2,7 @@
 # *** WARNING: this file was generated by the Pulumi SDK Generator. ***
 # *** Do not edit by hand unless you're certain you know what you are doing! ***
 
+import copy
 import warnings
 import pulumi
 import pulumi.runtime
@@ -16,7 +17,7 @@ class RandomStringArgs:
                  length: pulumi.Input[int]):
         """
         The set of arguments for constructing a RandomString resource.
-        :param pulumi.Input[int] length: Length of the string to generate.
+        :param pulumi.Input[int] length: Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
         """
         pulumi.set(__self__, "length", length)
 
@@ -24,7 +25,7 @@ class RandomStringArgs:
     @pulumi.getter
     def length(self) -> pulumi.Input[int]:
         """
-        Length of the string to generate.
+        Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
         """
         return pulumi.get(self, "length")
 
@@ -45,7 +46,7 @@ class RandomString(pulumi.CustomResource):
 
         :param str resource_name: The name of the resource.
         :param pulumi.ResourceOptions opts: Options for the resource.
-        :param pulumi.Input[int] length: Length of the string to generate.
+        :param pulumi.Input[int] length: Length of the string to generate. Must be at least 1. Changing this property forces the resource to be replaced.
         """
         ...
     @overload
@@ -73,12 +74,9 @@ class RandomString(pulumi.CustomResource):
                  opts: Optional[pulumi.ResourceOptions] = None,
                  length: Optional[pulumi.Input[int]] = None,
                  __props__=None):
-        if opts is None:
-            opts = pulumi.ResourceOptions()
+        opts = pulumi.ResourceOptions.merge(_utilities.get_resource_opts_defaults(), opts)
         if not isinstance(opts, pulumi.ResourceOptions):
             raise TypeError('Expected resource options to be a ResourceOptions instance')
-        if opts.version is None:
-            opts.version = _utilities.get_version()
         if opts.id is None:
             if __props__ is not None:
                 raise TypeError('__props__ is only valid when passed in combination with a valid opts.id to get an existing resource')